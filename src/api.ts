@@ -0,0 +1,20 @@
+import * as vscode from 'vscode';
+import { GoErrorCollapseApi } from './types';
+import { getDetector } from './detector';
+import { buildFindings } from './findings';
+import { ConfigManager, DEFAULT_CONFIG } from './config';
+
+/**
+ * Create the API object exposed to other extensions
+ * Obtain it via vscode.extensions.getExtension('mohsinkaleem.go-error-collapse').exports
+ */
+export function createApi(): GoErrorCollapseApi {
+    return {
+        detectErrorBlocks: (document: vscode.TextDocument) => getDetector().detectErrorBlocks(document),
+        detectInText: (text: string) => getDetector().detectInText(text),
+        getFindings: (document: vscode.TextDocument) =>
+            buildFindings(document, getDetector().detectErrorBlocks(document)),
+        getConfig: () => ConfigManager.getConfig(),
+        defaultConfig: DEFAULT_CONFIG,
+    };
+}
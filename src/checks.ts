@@ -0,0 +1,227 @@
+import * as vscode from 'vscode';
+import { CheckInfo } from './types';
+import { getOutputChannel } from './outputChannel';
+import { ConfigManager } from './config';
+
+/**
+ * Registry of every diagnostic check
+ * Diagnostic codes, documentation and the check list all come from here
+ * Keep the enabledChecks/disabledChecks enums in package.json in sync with these ids
+ */
+export const CHECKS: CheckInfo[] = [
+    {
+        id: 'log-format',
+        description: 'log.Fatal, log.Print or log.Panic called with a format string; use the ...f variant',
+        enabledByDefault: true,
+        before: ['log.Fatal("reading %s: %v", name, err)'],
+        after: ['log.Fatalf("reading %s: %v", name, err)'],
+    },
+    {
+        id: 'unreachable',
+        description: 'Statements after log.Fatal, log.Panic, os.Exit or panic in the same error block',
+        enabledByDefault: true,
+        before: [
+            'if err != nil {',
+            '    log.Fatal(err)',
+            '    return err',
+            '}',
+        ],
+        after: [
+            'if err != nil {',
+            '    log.Fatal(err)',
+            '}',
+        ],
+    },
+    {
+        id: 'errorf-wrap',
+        description: 'fmt.Errorf formats an error with %v or %s; use %w so errors.Is and errors.As still work',
+        enabledByDefault: true,
+        before: ['return fmt.Errorf("loading config: %v", err)'],
+        after: ['return fmt.Errorf("loading config: %w", err)'],
+    },
+    {
+        id: 'swallowed-error',
+        description: 'Error block is empty or returns nil in place of the checked error',
+        enabledByDefault: true,
+        before: [
+            'if err != nil {',
+            '    return nil',
+            '}',
+        ],
+        after: [
+            'if err != nil {',
+            '    return err',
+            '}',
+        ],
+    },
+    {
+        id: 'stale-error-check',
+        description: 'Error variable checked again without being assigned since the previous check',
+        enabledByDefault: true,
+        before: [
+            'a, err := load()',
+            'if err != nil {',
+            '    return err',
+            '}',
+            'b := parse(a)',
+            'if err != nil {',
+            '    return err',
+            '}',
+        ],
+        after: [
+            'a, err := load()',
+            'if err != nil {',
+            '    return err',
+            '}',
+            'b, err := parse(a)',
+            'if err != nil {',
+            '    return err',
+            '}',
+        ],
+    },
+    {
+        id: 'panic-in-error-func',
+        description: 'panic(err) in a function that can return the error instead',
+        enabledByDefault: false,
+        before: [
+            'func load(path string) (*Config, error) {',
+            '    data, err := os.ReadFile(path)',
+            '    if err != nil {',
+            '        panic(err)',
+            '    }',
+        ],
+        after: [
+            'func load(path string) (*Config, error) {',
+            '    data, err := os.ReadFile(path)',
+            '    if err != nil {',
+            '        return nil, err',
+            '    }',
+        ],
+    },
+    {
+        id: 'error-var-name',
+        description: 'Error variable named e, er or error instead of err',
+        enabledByDefault: false,
+        before: [
+            'v, e := parse(s)',
+            'if e != nil {',
+            '    return e',
+            '}',
+        ],
+        after: [
+            'v, err := parse(s)',
+            'if err != nil {',
+            '    return err',
+            '}',
+        ],
+    },
+];
+
+// Unknown ids already reported, so each typo is only logged once
+const reportedUnknownChecks: Set<string> = new Set();
+
+/**
+ * Check whether a diagnostic check should run
+ * Starts from the default, then applies enabledChecks, then disabledChecks
+ */
+export function isCheckEnabled(id: string): boolean {
+    const check = CHECKS.find(c => c.id === id);
+    let enabled = check ? check.enabledByDefault : false;
+    
+    if (ConfigManager.enabledChecks.includes(id)) {
+        enabled = true;
+    }
+    if (ConfigManager.disabledChecks.includes(id)) {
+        enabled = false;
+    }
+    
+    return enabled;
+}
+
+/**
+ * Report check ids in the settings that do not exist
+ * Shown as an error, since a typo would otherwise silently leave a check at its default
+ */
+export function reportUnknownChecks(): void {
+    const configured = [...ConfigManager.enabledChecks, ...ConfigManager.disabledChecks];
+    const unknown: string[] = [];
+    
+    for (const id of configured) {
+        if (!CHECKS.some(check => check.id === id) && !reportedUnknownChecks.has(id)) {
+            reportedUnknownChecks.add(id);
+            unknown.push(id);
+            getOutputChannel().appendLine(
+                `Unknown diagnostic check "${id}" in goErrorCollapse.enabledChecks/disabledChecks`
+            );
+        }
+    }
+    
+    if (unknown.length > 0) {
+        vscode.window.showErrorMessage(
+            `Go Error Collapse: Unknown check id(s) in goErrorCollapse.enabledChecks/disabledChecks: ${unknown.join(', ')}. ` +
+            `Known checks: ${CHECKS.map(check => check.id).join(', ')}`
+        );
+    }
+}
+
+/**
+ * Print a check's description with a before/after example to the output channel
+ */
+function printExplanation(check: CheckInfo): void {
+    const output = getOutputChannel();
+    const state = isCheckEnabled(check.id) ? 'on' : 'off';
+    
+    output.appendLine(`${check.id} (${state})`);
+    output.appendLine(`  ${check.description}`);
+    output.appendLine('');
+    output.appendLine('  Before:');
+    check.before.forEach(line => output.appendLine(`    ${line}`));
+    output.appendLine('  After:');
+    check.after.forEach(line => output.appendLine(`    ${line}`));
+    output.appendLine('');
+}
+
+/**
+ * Ask for a check and explain it, or every check when "All checks" is picked
+ */
+export async function explainCheck(): Promise<void> {
+    const all = 'All checks';
+    const picked = await vscode.window.showQuickPick(
+        [...CHECKS.map(check => ({ label: check.id, description: check.description })), { label: all }],
+        { placeHolder: 'Select a diagnostic check to explain' }
+    );
+    
+    if (!picked) {
+        return;
+    }
+    
+    const output = getOutputChannel();
+    output.clear();
+    CHECKS
+        .filter(check => picked.label === all || check.id === picked.label)
+        .forEach(printExplanation);
+    output.show(true);
+}
+
+/**
+ * Print every check with its default and current state to the output channel
+ */
+export function listChecks(): void {
+    const output = getOutputChannel();
+    const width = Math.max(...CHECKS.map(check => check.id.length));
+    
+    output.clear();
+    output.appendLine('Diagnostic checks (toggle with goErrorCollapse.enabledChecks / goErrorCollapse.disabledChecks)');
+    output.appendLine('');
+    output.appendLine(`${'Id'.padEnd(width)}  Default  Current  Description`);
+    
+    for (const check of CHECKS) {
+        const byDefault = check.enabledByDefault ? 'on' : 'off';
+        const current = isCheckEnabled(check.id) ? 'on' : 'off';
+        output.appendLine(
+            `${check.id.padEnd(width)}  ${byDefault.padEnd(7)}  ${current.padEnd(7)}  ${check.description}`
+        );
+    }
+    
+    output.show(true);
+}
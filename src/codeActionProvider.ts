@@ -0,0 +1,315 @@
+import * as vscode from 'vscode';
+import { getDetector, splitLines } from './detector';
+import { DiagnosticsManager, findCheckedErrorVar, findUnwrappedErrorfVerbs, getDiagnosticsManager } from './diagnostics';
+import { ConfigManager } from './config';
+
+// Checks whose quick fixes are combined by the fix-all action, in the order they are applied
+// Dead code goes first so no other fix edits lines about to be deleted;
+// the panic fix is left out because the replacement return needs a human to check the values
+const FIX_ALL_ORDER: readonly string[] = ['unreachable', 'log-format', 'errorf-wrap'];
+
+/**
+ * Check that deleting the lines leaves braces, brackets and parentheses balanced
+ */
+function isBalanced(lines: string[]): boolean {
+    let depth = 0;
+    for (const line of lines) {
+        const code = line.replace(/"(?:[^"\\]|\\.)*"|`[^`]*`|'(?:[^'\\]|\\.)*'|\/\/.*$/g, '');
+        for (const char of code) {
+            if ('({['.includes(char)) {
+                depth++;
+            } else if (')}]'.includes(char) && --depth < 0) {
+                return false;
+            }
+        }
+    }
+    return depth === 0;
+}
+
+/**
+ * Zero value literal for an unnamed result type, or null if it needs type information
+ */
+function zeroValue(type: string): string | null {
+    if (/^(\*|\[\]|map\[|chan\b|func\b|interface\s*\{|any$|error$)/.test(type)) {
+        return 'nil';
+    }
+    if (type === 'string') {
+        return '""';
+    }
+    if (type === 'bool') {
+        return 'false';
+    }
+    if (/^(u?int(8|16|32|64)?|uintptr|byte|rune|float(32|64)|complex(64|128))$/.test(type)) {
+        return '0';
+    }
+    return null;
+}
+
+/**
+ * Code action provider offering quick fixes for this extension's diagnostics
+ * Edits are only applied when the user picks an action
+ */
+export class GoErrorCodeActionProvider implements vscode.CodeActionProvider {
+    public static readonly fixAllKind = vscode.CodeActionKind.SourceFixAll.append('goErrorCollapse');
+    public static readonly providedCodeActionKinds = [
+        vscode.CodeActionKind.QuickFix,
+        GoErrorCodeActionProvider.fixAllKind,
+    ];
+    
+    /**
+     * Provide quick fixes for the diagnostics in the requested range
+     */
+    public provideCodeActions(
+        document: vscode.TextDocument,
+        _range: vscode.Range | vscode.Selection,
+        context: vscode.CodeActionContext,
+        _token: vscode.CancellationToken
+    ): vscode.ProviderResult<vscode.CodeAction[]> {
+        // Fix-all requests (e.g. editor.codeActionsOnSave) cover the whole document
+        if (context.only?.contains(GoErrorCodeActionProvider.fixAllKind)) {
+            const fixAll = this.fixAll(document);
+            return fixAll ? [fixAll] : [];
+        }
+        
+        const actions: vscode.CodeAction[] = [];
+        
+        for (const diagnostic of context.diagnostics) {
+            if (diagnostic.source !== DiagnosticsManager.SOURCE) {
+                continue;
+            }
+            
+            const action = this.findQuickFix(document, diagnostic);
+            if (action) {
+                actions.push(action);
+            }
+        }
+        
+        return actions;
+    }
+    
+    /**
+     * Find the quick fix for one of this extension's diagnostics
+     */
+    private findQuickFix(document: vscode.TextDocument, diagnostic: vscode.Diagnostic): vscode.CodeAction | null {
+        switch (diagnostic.code) {
+            case 'log-format':
+                return this.fixLogFormat(document, diagnostic);
+            case 'unreachable':
+                return this.removeUnreachable(document, diagnostic);
+            case 'errorf-wrap':
+                return this.fixErrorfWrap(document, diagnostic);
+            case 'error-var-name':
+                return this.renameToErr(document, diagnostic);
+            case 'panic-in-error-func':
+                return this.returnInsteadOfPanic(document, diagnostic);
+            default:
+                return null;
+        }
+    }
+    
+    /**
+     * Combine the unreachable, log-format and errorf-wrap fixes for the whole document into one action
+     * Diagnostics are recomputed from the current text, since the published ones trail edits;
+     * a fix overlapping an earlier one is left out and offered again once the document is re-checked
+     */
+    private fixAll(document: vscode.TextDocument): vscode.CodeAction | null {
+        // Same files as the published diagnostics, so generated code is never edited on save
+        const text = document.getText();
+        const detector = getDetector();
+        if (!ConfigManager.enableDiagnostics || detector.exceedsMaxFileSize(text) || detector.skipsGeneratedFile(text)) {
+            return null;
+        }
+        
+        const edits: vscode.TextEdit[] = [];
+        
+        const diagnostics = getDiagnosticsManager().computeDiagnostics(text)
+            .filter(diagnostic => FIX_ALL_ORDER.includes(String(diagnostic.code)))
+            .sort((a, b) => FIX_ALL_ORDER.indexOf(String(a.code)) - FIX_ALL_ORDER.indexOf(String(b.code)));
+        
+        for (const diagnostic of diagnostics) {
+            
+            // Never rename anything but the call the check matched
+            if (diagnostic.code === 'log-format' && !/^log\.(Fatal|Print|Panic)(ln)?$/.test(document.getText(diagnostic.range))) {
+                continue;
+            }
+            
+            const edit = this.findQuickFix(document, diagnostic)?.edit?.get(document.uri)[0];
+            if (edit && !edits.some(other => other.range.intersection(edit.range))) {
+                edits.push(edit);
+            }
+        }
+        
+        if (edits.length === 0) {
+            return null;
+        }
+        
+        const action = new vscode.CodeAction('Fix all Go Error Collapse problems', GoErrorCodeActionProvider.fixAllKind);
+        action.edit = new vscode.WorkspaceEdit();
+        action.edit.set(document.uri, edits);
+        
+        return action;
+    }
+    
+    /**
+     * Replace log.Fatal/log.Print/log.Panic (or the ...ln form) with the ...f variant
+     */
+    private fixLogFormat(document: vscode.TextDocument, diagnostic: vscode.Diagnostic): vscode.CodeAction {
+        const callName = document.getText(diagnostic.range);
+        const replacement = `${callName.replace(/ln$/, '')}f`;
+        
+        const action = new vscode.CodeAction(`Use ${replacement}`, vscode.CodeActionKind.QuickFix);
+        action.edit = new vscode.WorkspaceEdit();
+        action.edit.replace(document.uri, diagnostic.range, replacement);
+        action.diagnostics = [diagnostic];
+        action.isPreferred = true;
+        
+        return action;
+    }
+    
+    /**
+     * Switch a fmt.Errorf verb to %w
+     * Only offered when the call formats exactly one error and it is the variable
+     * checked by the enclosing error block, so its type is known to be error
+     */
+    private fixErrorfWrap(
+        document: vscode.TextDocument,
+        diagnostic: vscode.Diagnostic
+    ): vscode.CodeAction | null {
+        const { start, end } = diagnostic.range;
+        const checkedVar = findCheckedErrorVar(getDetector().findErrorChecks(document.getText()), start.line);
+        const found = findUnwrappedErrorfVerbs(document.lineAt(start.line).text, checkedVar)
+            .find(verb => verb.start === start.character && verb.end === end.character);
+        
+        if (!found || !found.checked || found.errorArgs !== 1) {
+            return null;
+        }
+        
+        const action = new vscode.CodeAction('Use %w to wrap the error', vscode.CodeActionKind.QuickFix);
+        action.edit = new vscode.WorkspaceEdit();
+        action.edit.replace(document.uri, diagnostic.range, '%w');
+        action.diagnostics = [diagnostic];
+        action.isPreferred = true;
+        
+        return action;
+    }
+    
+    /**
+     * Replace panic(err) with a return of the error
+     * Only offered when every other result is unnamed and has a literal zero value
+     */
+    private returnInsteadOfPanic(
+        document: vscode.TextDocument,
+        diagnostic: vscode.Diagnostic
+    ): vscode.CodeAction | null {
+        const errorVar = document.getText(diagnostic.range).match(/^panic\(\s*(.+?)\s*\)$/);
+        const resultTypes = getDetector().findEnclosingResultTypes(
+            splitLines(document.getText()),
+            diagnostic.range.start.line
+        );
+        if (!errorVar || !resultTypes) {
+            return null;
+        }
+        
+        const zeros = resultTypes.slice(0, -1).map(zeroValue);
+        if (zeros.some(zero => zero === null)) {
+            return null;
+        }
+        
+        const replacement = `return ${[...zeros, errorVar[1]].join(', ')}`;
+        const action = new vscode.CodeAction(`Use ${replacement}`, vscode.CodeActionKind.QuickFix);
+        action.edit = new vscode.WorkspaceEdit();
+        action.edit.replace(document.uri, diagnostic.range, replacement);
+        action.diagnostics = [diagnostic];
+        
+        return action;
+    }
+    
+    /**
+     * Rename an error variable to err through the Go language server
+     * The rename is scope-aware and refused by gopls if it would conflict
+     */
+    private renameToErr(document: vscode.TextDocument, diagnostic: vscode.Diagnostic): vscode.CodeAction {
+        const action = new vscode.CodeAction('Rename to err', vscode.CodeActionKind.QuickFix);
+        action.command = {
+            command: 'goErrorCollapse.renameErrorVariable',
+            title: 'Rename to err',
+            arguments: [document.uri, diagnostic.range.start],
+        };
+        action.diagnostics = [diagnostic];
+        
+        return action;
+    }
+    
+    /**
+     * Delete everything from the unreachable statement to the end of its error block
+     * The deletion stops before the block's closing "}" (or "} else") and is not offered
+     * if it would remove an unmatched brace
+     */
+    private removeUnreachable(
+        document: vscode.TextDocument,
+        diagnostic: vscode.Diagnostic
+    ): vscode.CodeAction | null {
+        const line = diagnostic.range.start.line;
+        const check = getDetector().findErrorChecks(document.getText())
+            .find(c => c.bodyStartLine <= line && line < c.endLine);
+        
+        if (!check) {
+            return null;
+        }
+        
+        const removed: string[] = [];
+        for (let i = line; i < check.endLine; i++) {
+            const text = document.lineAt(i).text;
+            if (text.trim().startsWith('}') && text.length - text.trimStart().length <= check.indentation.length) {
+                break;
+            }
+            removed.push(text);
+        }
+        if (removed.length === 0 || !isBalanced(removed)) {
+            return null;
+        }
+        
+        const action = new vscode.CodeAction('Remove unreachable code', vscode.CodeActionKind.QuickFix);
+        action.edit = new vscode.WorkspaceEdit();
+        action.edit.delete(document.uri, new vscode.Range(line, 0, line + removed.length, 0));
+        action.diagnostics = [diagnostic];
+        
+        return action;
+    }
+}
+
+/**
+ * Rename the variable at a position to err using the document's rename provider
+ */
+export async function renameErrorVariable(uri: vscode.Uri, position: vscode.Position): Promise<void> {
+    try {
+        const edit = await vscode.commands.executeCommand<vscode.WorkspaceEdit>(
+            'vscode.executeDocumentRenameProvider', uri, position, 'err'
+        );
+        if (edit && edit.size > 0) {
+            await vscode.workspace.applyEdit(edit);
+            return;
+        }
+    } catch (error) {
+        const message = error instanceof Error ? error.message : String(error);
+        vscode.window.showWarningMessage(`Go Error Collapse: Could not rename to err: ${message}`);
+        return;
+    }
+    
+    vscode.window.showWarningMessage('Go Error Collapse: Could not rename to err; is the Go language server running?');
+}
+
+/**
+ * Register the code action provider
+ */
+export function registerCodeActionProvider(context: vscode.ExtensionContext): vscode.Disposable {
+    const disposable = vscode.languages.registerCodeActionsProvider(
+        { language: 'go', scheme: 'file' },
+        new GoErrorCodeActionProvider(),
+        { providedCodeActionKinds: GoErrorCodeActionProvider.providedCodeActionKinds }
+    );
+    
+    context.subscriptions.push(disposable);
+    
+    return disposable;
+}
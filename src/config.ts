@@ -1,6 +1,43 @@
 import * as vscode from 'vscode';
 import { ExtensionConfig } from './types';
 
+/**
+ * Freeze an object and the arrays it holds
+ * The default arrays are handed to config.get and to API consumers, so they must not be shared mutably
+ */
+function deepFreeze<T extends object>(value: T): Readonly<T> {
+    for (const field of Object.values(value)) {
+        if (Array.isArray(field)) {
+            Object.freeze(field);
+        }
+    }
+    return Object.freeze(value);
+}
+
+/**
+ * Built-in defaults, used for every setting the user has not set
+ * Keep in sync with the defaults declared in package.json
+ */
+export const DEFAULT_CONFIG: Readonly<ExtensionConfig> = deepFreeze<ExtensionConfig>({
+    autoCollapseOnOpen: true,
+    autoCollapseOnSave: true,
+    errorOpacity: 0.5,
+    showCollapsedHint: true,
+    errorPatterns: ['err', 'error'],
+    enableDiagnostics: true,
+    exclude: ['**/vendor/**', '**/testdata/**'],
+    testFiles: 'include',
+    processGeneratedFiles: false,
+    passthroughOnly: false,
+    minBlocks: 1,
+    trace: false,
+    optIn: false,
+    customStatementPatterns: [],
+    enabledChecks: [],
+    disabledChecks: [],
+    maxFileSize: 2 * 1024 * 1024,
+});
+
 /**
  * Configuration manager for the Go Error Collapse extension
  */
@@ -14,11 +51,23 @@ export class ConfigManager {
         const config = vscode.workspace.getConfiguration(ConfigManager.CONFIG_SECTION);
         
         return {
-            autoCollapseOnOpen: config.get<boolean>('autoCollapseOnOpen', true),
-            autoCollapseOnSave: config.get<boolean>('autoCollapseOnSave', true),
-            errorOpacity: config.get<number>('errorOpacity', 0.5),
-            showCollapsedHint: config.get<boolean>('showCollapsedHint', true),
-            errorPatterns: config.get<string[]>('errorPatterns', ['err', 'error']),
+            autoCollapseOnOpen: config.get<boolean>('autoCollapseOnOpen', DEFAULT_CONFIG.autoCollapseOnOpen),
+            autoCollapseOnSave: config.get<boolean>('autoCollapseOnSave', DEFAULT_CONFIG.autoCollapseOnSave),
+            errorOpacity: config.get<number>('errorOpacity', DEFAULT_CONFIG.errorOpacity),
+            showCollapsedHint: config.get<boolean>('showCollapsedHint', DEFAULT_CONFIG.showCollapsedHint),
+            errorPatterns: config.get<string[]>('errorPatterns', DEFAULT_CONFIG.errorPatterns),
+            enableDiagnostics: config.get<boolean>('enableDiagnostics', DEFAULT_CONFIG.enableDiagnostics),
+            exclude: config.get<string[]>('exclude', DEFAULT_CONFIG.exclude),
+            testFiles: config.get<ExtensionConfig['testFiles']>('testFiles', DEFAULT_CONFIG.testFiles),
+            processGeneratedFiles: config.get<boolean>('processGeneratedFiles', DEFAULT_CONFIG.processGeneratedFiles),
+            passthroughOnly: config.get<boolean>('passthroughOnly', DEFAULT_CONFIG.passthroughOnly),
+            minBlocks: config.get<number>('minBlocks', DEFAULT_CONFIG.minBlocks),
+            trace: config.get<boolean>('trace', DEFAULT_CONFIG.trace),
+            optIn: config.get<boolean>('optIn', DEFAULT_CONFIG.optIn),
+            customStatementPatterns: config.get<string[]>('customStatementPatterns', DEFAULT_CONFIG.customStatementPatterns),
+            enabledChecks: config.get<string[]>('enabledChecks', DEFAULT_CONFIG.enabledChecks),
+            disabledChecks: config.get<string[]>('disabledChecks', DEFAULT_CONFIG.disabledChecks),
+            maxFileSize: config.get<number>('maxFileSize', DEFAULT_CONFIG.maxFileSize),
         };
     }
     
@@ -57,16 +106,115 @@ export class ConfigManager {
         return this.getConfig().errorPatterns;
     }
     
+    /**
+     * Check if diagnostics are enabled
+     */
+    public static get enableDiagnostics(): boolean {
+        return this.getConfig().enableDiagnostics;
+    }
+    
+    /**
+     * Get the glob patterns excluded from workspace scans
+     */
+    public static get exclude(): string[] {
+        return this.getConfig().exclude;
+    }
+    
+    /**
+     * Get whether workspace scans include, skip or only cover test files
+     */
+    public static get testFiles(): ExtensionConfig['testFiles'] {
+        return this.getConfig().testFiles;
+    }
+    
+    /**
+     * Check if generated files should be processed
+     */
+    public static get processGeneratedFiles(): boolean {
+        return this.getConfig().processGeneratedFiles;
+    }
+    
+    /**
+     * Check if only pass-through returns should be collapsed
+     */
+    public static get passthroughOnly(): boolean {
+        return this.getConfig().passthroughOnly;
+    }
+    
+    /**
+     * Get the minimum block count for auto-collapse
+     */
+    public static get minBlocks(): number {
+        return this.getConfig().minBlocks;
+    }
+    
+    /**
+     * Check if detection decisions should be logged
+     */
+    public static get trace(): boolean {
+        return this.getConfig().trace;
+    }
+    
+    /**
+     * Check if collapsing requires an //errcollapse:enable directive
+     */
+    public static get optIn(): boolean {
+        return this.getConfig().optIn;
+    }
+    
+    /**
+     * Get the user-defined statement patterns
+     */
+    public static get customStatementPatterns(): string[] {
+        return this.getConfig().customStatementPatterns;
+    }
+    
+    /**
+     * Get the diagnostic checks explicitly turned on
+     */
+    public static get enabledChecks(): string[] {
+        return this.getConfig().enabledChecks;
+    }
+    
+    /**
+     * Get the diagnostic checks explicitly turned off
+     */
+    public static get disabledChecks(): string[] {
+        return this.getConfig().disabledChecks;
+    }
+    
+    /**
+     * Get the file size limit in bytes (0 = no limit)
+     */
+    public static get maxFileSize(): number {
+        return this.getConfig().maxFileSize;
+    }
+    
     /**
      * Build regex pattern for error variable names
      */
     public static getErrorVariablePattern(): string {
-        const patterns = this.errorPatterns;
+        // Entries are regex fragments as in 1.0.0; one that does not compile is matched literally
+        const patterns = this.errorPatterns.map(p => ConfigManager.isValidRegex(p)
+            ? `(?:${p})`
+            : p.replace(/[.*+?^${}()|[\]\\]/g, '\\$&'));
         // Create pattern that matches err, error, someErr, someError, etc.
         const patternParts = patterns.map(p => `\\w*${p}\\w*`);
         return `(${patternParts.join('|')})`;
     }
     
+    /**
+     * Check whether a string compiles as a regular expression
+     */
+    private static isValidRegex(source: string): boolean {
+        try {
+            new RegExp(source);
+            return true;
+        } catch {
+            return false;
+        }
+    }
+    
     /**
      * Register configuration change listener
      */
@@ -63,6 +63,15 @@ export class DecorationManager {
      * Shows the collapsed text as a decoration after the folded line
      */
     public applyCollapsedHints(editor: vscode.TextEditor, blocks: ErrorBlock[]): void {
+        // Clear existing hints
+        this.clearHints(editor.document.uri.toString());
+        this.addCollapsedHints(editor, blocks);
+    }
+    
+    /**
+     * Add inline hints for the given blocks, keeping the hints of other blocks
+     */
+    public addCollapsedHints(editor: vscode.TextEditor, blocks: ErrorBlock[]): void {
         if (!ConfigManager.showCollapsedHint) {
             return;
         }
@@ -70,22 +79,24 @@ export class DecorationManager {
         const document = editor.document;
         const uri = document.uri.toString();
         
-        // Clear existing hints
-        this.clearHints(uri);
-        
         // Create decoration for each block - subtle/dimmed to blend in
         for (const block of blocks) {
+            this.removeCollapsedHint(uri, block.startLine);
+            
+            // Keep a comment on the closing brace visible, since the fold hides that line
+            const comment = block.closingComment ? ` } ${block.closingComment}` : '';
             const hintDecoration = vscode.window.createTextEditorDecorationType({
                 after: {
-                    contentText: ` ${block.bodyStatement}`,
+                    contentText: ` ${block.bodyStatement}${comment}`,
                     color: new vscode.ThemeColor('editorLineNumber.foreground'),
                 },
                 isWholeLine: false,
             });
             
             // Position after the opening brace - use zero-width range
+            // The brace may be followed by a trailing comment
             const ifLine = document.lineAt(block.startLine);
-            const braceIndex = ifLine.text.lastIndexOf('{');
+            const braceIndex = ifLine.text.search(/\{\s*(\/\/.*)?$/);
             
             const range = new vscode.Range(
                 block.startLine, braceIndex + 1,
@@ -97,6 +108,15 @@ export class DecorationManager {
         }
     }
     
+    /**
+     * Remove the hint of the block starting at a line
+     */
+    public removeCollapsedHint(uri: string, startLine: number): void {
+        const key = `${uri}:${startLine}`;
+        this.hintDecorations.get(key)?.dispose();
+        this.hintDecorations.delete(key);
+    }
+    
     /**
      * Clear all hints for a document
      */
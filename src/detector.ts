@@ -1,6 +1,24 @@
 import * as vscode from 'vscode';
-import { ErrorBlock, DocumentCache } from './types';
+import {
+    ErrorBlock,
+    ErrorCheck,
+    DocumentCache,
+    Rejection,
+    SkippedBlock,
+    ErrorHandlingPattern,
+    PatternCounts,
+    FunctionRange,
+} from './types';
 import { ConfigManager } from './config';
+import { getOutputChannel } from './outputChannel';
+
+/**
+ * Split source text into lines, accepting both LF and CRLF endings
+ * The line terminator is never part of a line, so ranges stop before it
+ */
+export function splitLines(text: string): string[] {
+    return text.split(/\r?\n/);
+}
 
 /**
  * Error block detector using regex-based pattern matching
@@ -9,9 +27,14 @@ import { ConfigManager } from './config';
 export class ErrorBlockDetector {
     private cache: Map<string, DocumentCache> = new Map();
     private debounceTimers: Map<string, NodeJS.Timeout> = new Map();
+    private reportedInvalidPatterns: Set<string> = new Set();
+    private reportedLargeFiles: Set<string> = new Set();
     private static readonly DEBOUNCE_DELAY = 150;
     private static readonly CACHE_TTL = 5000; // 5 seconds
     
+    // "errs = append(errs, err)" or "err = errors.Join(err, e)"
+    private static readonly ACCUMULATE_PATTERN = /^[\w.]+\s*=\s*(append|errors\.Join)\(/;
+    
     /**
      * Detect all error blocks in a document
      * Uses caching for performance
@@ -28,7 +51,7 @@ export class ErrorBlockDetector {
         }
         
         // Perform detection
-        const blocks = this.performDetection(document);
+        const blocks = this.detectSafely(document.getText(), vscode.workspace.asRelativePath(document.uri));
         
         // Update cache
         this.cache.set(uri, {
@@ -81,55 +104,311 @@ export class ErrorBlockDetector {
         this.debounceTimers.clear();
     }
     
+    /**
+     * Detect error blocks in raw source text (uncached)
+     * Used for files that are not open in an editor
+     */
+    public detectInText(text: string, fileName: string = '<text>'): ErrorBlock[] {
+        return this.detectSafely(text, fileName);
+    }
+    
+    /**
+     * Detect error blocks in raw source text and report the candidates that were not collapsed
+     * Uncached; used by the JSON export
+     */
+    public analyzeText(
+        text: string,
+        fileName: string = '<text>'
+    ): { blocks: ErrorBlock[]; skipped: SkippedBlock[] } {
+        const skipped: SkippedBlock[] = [];
+        const blocks = this.detectSafely(text, fileName, skipped);
+        return { blocks, skipped };
+    }
+    
+    /**
+     * Run detection, reporting failures instead of throwing
+     * Unexpected input must never break folding or the commands
+     */
+    private detectSafely(text: string, fileName: string, skipped?: SkippedBlock[]): ErrorBlock[] {
+        try {
+            return this.performDetection(text, fileName, skipped);
+        } catch (error) {
+            const message = error instanceof Error ? error.message : String(error);
+            getOutputChannel().appendLine(`${fileName}: error block detection failed: ${message}`);
+            return [];
+        }
+    }
+    
+    /**
+     * Check whether text is over the goErrorCollapse.maxFileSize limit
+     */
+    public exceedsMaxFileSize(text: string): boolean {
+        const maxFileSize = ConfigManager.maxFileSize;
+        return maxFileSize > 0 && Buffer.byteLength(text, 'utf8') > maxFileSize;
+    }
+    
+    /**
+     * Check whether text is generated code that goErrorCollapse.processGeneratedFiles leaves alone
+     */
+    public skipsGeneratedFile(text: string): boolean {
+        return !ConfigManager.processGeneratedFiles && this.isGeneratedFile(splitLines(text));
+    }
+    
+    /**
+     * Find every "if err != nil {" block regardless of collapse settings
+     * Used by diagnostics, which also apply to blocks that stay expanded
+     */
+    public findErrorChecks(text: string): ErrorCheck[] {
+        return this.scanErrorChecks(splitLines(text));
+    }
+    
+    /**
+     * Find the top-level functions and methods in raw source text
+     * gofmt places the declaration and its closing brace at column 0
+     */
+    public findFunctions(text: string): FunctionRange[] {
+        const lines = splitLines(text);
+        const functions: FunctionRange[] = [];
+        
+        for (let i = 0; i < lines.length; i++) {
+            const match = lines[i].match(/^func\s+(?:\([^)]*\)\s*)?(\w+)/);
+            if (!match) {
+                continue;
+            }
+            
+            // The signature may span lines; it ends where its parentheses balance
+            let signatureEnd = i;
+            for (let depth = 0; signatureEnd < lines.length - 1; signatureEnd++) {
+                depth += (lines[signatureEnd].match(/\(/g) || []).length - (lines[signatureEnd].match(/\)/g) || []).length;
+                if (depth <= 0) {
+                    break;
+                }
+            }
+            
+            // A declaration without a body (an assembly stub) has no "{", and must not claim the next function's body
+            if (!/[{}]\s*(\/\/.*)?$/.test(lines[signatureEnd])) {
+                i = signatureEnd;
+                continue;
+            }
+            
+            // A one-line function closes on its own line
+            let end = i;
+            if (!/\}\s*(\/\/.*)?$/.test(lines[i])) {
+                end = lines.findIndex((line, index) => index > i && /^\}/.test(line));
+                if (end === -1) {
+                    end = lines.length - 1;
+                }
+            }
+            
+            functions.push({ name: match[1], startLine: i, endLine: end });
+            i = end;
+        }
+        
+        return functions;
+    }
+    
+    /**
+     * Count how the error checks in raw source text handle their errors
+     * Covers every "if err != nil" block, including ones that are not collapsible
+     * Generated files and files over goErrorCollapse.maxFileSize are not counted
+     */
+    public countPatterns(text: string): PatternCounts {
+        const counts: PatternCounts = {};
+        if (this.exceedsMaxFileSize(text) || this.skipsGeneratedFile(text)) {
+            return counts;
+        }
+        
+        const lines = splitLines(text);
+        for (const check of this.scanErrorChecks(lines)) {
+            const pattern = this.classifyErrorCheck(lines, check);
+            counts[pattern] = (counts[pattern] || 0) + 1;
+        }
+        
+        return counts;
+    }
+    
+    /**
+     * Classify how an error check handles its error
+     * The first matching pattern wins, so a logged fatal error counts as fatal
+     */
+    public classifyErrorCheck(lines: string[], check: ErrorCheck): ErrorHandlingPattern {
+        const statements = check.bodyLines
+            .map(line => line.trim())
+            .filter(line => line.length > 0 && !line.startsWith('//'));
+        const returns = statements.filter(line => /^return\b/.test(line));
+        const logging = /^(log\.|fmt\.(Print|Fprint)|\w+\.(Error|Warn|Info|Debug|Print))/;
+        
+        if (statements.length === 0 || this.isSwallowedError(lines, check)) {
+            return 'swallowed';
+        }
+        if (statements.some(line => /^(log\.(Fatal|Panic)|os\.Exit\s*\(|panic\s*\()/.test(line))) {
+            return 'fatal';
+        }
+        if (statements.some(line => /^(t|b|f|tb)\.(Fatal|Fatalf|FailNow)\(/.test(line))) {
+            return 'test-fatal';
+        }
+        if (this.isPassthroughReturn(check.bodyLines, check.errorVar)) {
+            return 'passthrough';
+        }
+        
+        // "err = fmt.Errorf(...)" then "return ..., err" is a wrap in two steps, not a passthrough
+        const reassigned = statements.length === 2 &&
+            new RegExp(`(^return\\s+|,\\s*)${check.errorVar};?$`).test(statements[1])
+            ? statements[0].match(new RegExp(`^${check.errorVar}\\s*=\\s*(.+)$`))
+            : null;
+        const wrapped = reassigned ? reassigned[1] : returns[0];
+        if (returns.length === 1 && /\bfmt\.Errorf\(/.test(wrapped)) {
+            return /%w/.test(wrapped) ? 'wrapped-w' : 'wrapped-v';
+        }
+        if (returns.length === 1 && /\berrors\.(Wrap|Wrapf|WithMessage|WithStack)\(/.test(wrapped)) {
+            return 'wrapped-w';
+        }
+        if (statements.some(line => ErrorBlockDetector.ACCUMULATE_PATTERN.test(line))) {
+            return 'accumulated';
+        }
+        if (statements.some(line => logging.test(line))) {
+            return returns.length > 0 ? 'logged-and-returned' : 'logged-and-continued';
+        }
+        return 'other';
+    }
+    
     /**
      * Perform the actual detection logic
+     * Rejected candidates are appended to skipped when it is given
      */
-    private performDetection(document: vscode.TextDocument): ErrorBlock[] {
-        const text = document.getText();
-        const lines = text.split('\n');
+    private performDetection(text: string, fileName: string, skipped?: SkippedBlock[]): ErrorBlock[] {
         const blocks: ErrorBlock[] = [];
+        const trace = ConfigManager.trace
+            ? (line: number, message: string): void =>
+                getOutputChannel().appendLine(`${fileName}:${line + 1}: ${message}`)
+            : null;
+        
+        // Huge files (usually generated blobs) are skipped before splitting
+        if (this.exceedsMaxFileSize(text)) {
+            if (!this.reportedLargeFiles.has(fileName)) {
+                this.reportedLargeFiles.add(fileName);
+                getOutputChannel().appendLine(
+                    `${fileName}: skipped, larger than goErrorCollapse.maxFileSize (${ConfigManager.maxFileSize} bytes)`
+                );
+            }
+            return blocks;
+        }
+        
+        const lines = splitLines(text);
+        
+        // Leave generated code alone unless explicitly requested
+        if (!ConfigManager.processGeneratedFiles && this.isGeneratedFile(lines)) {
+            trace?.(0, 'skipped file: generated code');
+            return blocks;
+        }
+        
+        // Whole file opted out with //errcollapse:ignore-file
+        if (this.hasHeaderComment(lines, /^\/\/errcollapse:ignore-file\s*$/)) {
+            trace?.(0, 'skipped file: //errcollapse:ignore-file');
+            return blocks;
+        }
+        
+        const passthroughOnly = ConfigManager.passthroughOnly;
+        const customPatterns = this.compileCustomPatterns();
+        
+        // In opt-in mode only annotated files or functions are collapsed
+        const enableDirective = /^\/\/errcollapse:enable\s*$/;
+        const requireEnable = ConfigManager.optIn && !this.hasHeaderComment(lines, enableDirective);
+        
+        for (const check of this.scanErrorChecks(lines)) {
+            const { startLine, endLine, bodyStartLine, indentation, errorVar, bodyLines } = check;
+            
+            // Validate this is a simple error return
+            let rejection: Rejection | null = this.isIgnored(lines, startLine)
+                ? { reason: 'ignore-directive', detail: '//errcollapse:ignore directive' }
+                : check.hasElse
+                    ? { reason: 'has-else', detail: 'has an else clause' }
+                    : this.findRejectionReason(
+                        bodyLines, customPatterns, () => this.findDeferredCalls(lines, startLine)
+                    );
+            if (!rejection && this.isLabeled(lines, startLine)) {
+                rejection = { reason: 'labeled-statement', detail: 'labeled statement' };
+            }
+            if (!rejection && this.isSwallowedError(lines, check)) {
+                rejection = { reason: 'swallows-error', detail: `returns nil instead of ${errorVar}` };
+            }
+            if (!rejection && passthroughOnly && !this.isPassthroughReturn(bodyLines, errorVar)) {
+                rejection = {
+                    reason: 'not-passthrough',
+                    detail: `does not return ${errorVar} unmodified (passthroughOnly)`,
+                };
+            }
+            if (!rejection && requireEnable &&
+                !this.hasDocDirective(lines, this.findEnclosingFunction(lines, startLine), enableDirective)) {
+                rejection = { reason: 'not-opted-in', detail: 'function not marked //errcollapse:enable (optIn)' };
+            }
+            
+            if (rejection) {
+                trace?.(startLine, `rejected: ${rejection.detail}`);
+                skipped?.push({ startLine, endLine, indentation, ...rejection });
+            } else {
+                const bodyStatement = this.extractBodyStatement(bodyLines);
+                const closingComment = lines[endLine].match(/^\s*\}\s*(\/\/.*?)\s*$/)?.[1];
+                const collapsedText = this.generateCollapsedText(lines[startLine], bodyStatement, closingComment);
+                trace?.(startLine, `accepted: ${bodyStatement}`);
+                
+                blocks.push({
+                    startLine,
+                    endLine,
+                    bodyStartLine,
+                    indentation,
+                    collapsedText,
+                    bodyStatement,
+                    closingComment,
+                    fullRange: new vscode.Range(
+                        startLine, 0,
+                        endLine, lines[endLine].length
+                    )
+                });
+            }
+        }
+        
+        return blocks;
+    }
+    
+    /**
+     * Locate "if err != nil {" blocks and their bodies
+     * Blocks nested inside a matched block are not reported separately
+     */
+    private scanErrorChecks(lines: string[]): ErrorCheck[] {
+        const checks: ErrorCheck[] = [];
         
         // Build error variable pattern from config
         const errorVarPattern = ConfigManager.getErrorVariablePattern();
+        // An init statement ("if err := f(); err != nil") is allowed; only the condition is matched.
+        // A trailing line comment after the opening brace is allowed;
+        // it stays visible on the folded line
         const ifErrPattern = new RegExp(
-            `^(\\s*)if\\s+${errorVarPattern}\\s*!=\\s*nil\\s*\\{\\s*$`
+            `^(\\s*)if\\s+(?:[^;]+;\\s*)?${errorVarPattern}\\s*!=\\s*nil\\s*\\{\\s*(//.*)?$`
         );
         
         let i = 0;
         while (i < lines.length) {
-            const line = lines[i];
-            const match = line.match(ifErrPattern);
+            const match = lines[i].match(ifErrPattern);
             
             if (match) {
-                const startLine = i;
                 const indentation = match[1] || '';
                 
                 // Find the closing brace
                 const result = this.findBlockEnd(lines, i, indentation);
                 
                 if (result) {
-                    const { endLine, bodyLines, bodyStartLine } = result;
-                    
-                    // Validate this is a simple error return
-                    if (this.isSimpleErrorReturn(bodyLines)) {
-                        const bodyStatement = this.extractBodyStatement(bodyLines);
-                        const collapsedText = this.generateCollapsedText(line, bodyStatement);
-                        
-                        blocks.push({
-                            startLine,
-                            endLine,
-                            bodyStartLine,
-                            indentation,
-                            collapsedText,
-                            bodyStatement,
-                            fullRange: new vscode.Range(
-                                startLine, 0,
-                                endLine, lines[endLine].length
-                            )
-                        });
-                    }
+                    checks.push({
+                        startLine: i,
+                        endLine: result.endLine,
+                        bodyStartLine: result.bodyStartLine,
+                        indentation,
+                        errorVar: match[2],
+                        bodyLines: result.bodyLines,
+                        hasElse: result.hasElse
+                    });
                     
-                    i = endLine + 1;
+                    i = result.endLine + 1;
                     continue;
                 }
             }
@@ -137,17 +416,124 @@ export class ErrorBlockDetector {
             i++;
         }
         
-        return blocks;
+        return checks;
+    }
+    
+    /**
+     * Check for the standard generated-code header before the package clause
+     * See https://pkg.go.dev/cmd/go#hdr-Generate_Go_files_by_processing_source
+     */
+    private isGeneratedFile(lines: string[]): boolean {
+        return this.hasHeaderComment(lines, /^\/\/ Code generated .* DO NOT EDIT\.$/);
+    }
+    
+    /**
+     * Check for a comment line matching the pattern before the package clause
+     */
+    private hasHeaderComment(lines: string[], pattern: RegExp): boolean {
+        for (const line of lines) {
+            const trimmed = line.trim();
+            if (pattern.test(trimmed)) {
+                return true;
+            }
+            if (/^package\s/.test(trimmed)) {
+                return false;
+            }
+        }
+        return false;
+    }
+    
+    /**
+     * Check for an //errcollapse:ignore directive on the if line
+     * or on its own line directly above it
+     */
+    private isIgnored(lines: string[], ifLine: number): boolean {
+        const directive = /\/\/errcollapse:ignore(?![\w-])/;
+        if (directive.test(lines[ifLine])) {
+            return true;
+        }
+        return ifLine > 0 && /^\s*\/\/errcollapse:ignore(?![\w-])/.test(lines[ifLine - 1]);
+    }
+    
+    /**
+     * Check whether the if statement is the target of a label on the line above
+     */
+    private isLabeled(lines: string[], ifLine: number): boolean {
+        const label = ifLine > 0 ? lines[ifLine - 1].match(/^\s*(\w+):\s*$/) : null;
+        return label !== null && label[1] !== 'default';
+    }
+    
+    /**
+     * Find the top-level "func" line enclosing the given line, or -1
+     * gofmt places top-level declarations at column 0
+     */
+    private findEnclosingFunction(lines: string[], line: number): number {
+        for (let i = line; i >= 0; i--) {
+            if (/^func\b/.test(lines[i])) {
+                return i;
+            }
+        }
+        return -1;
+    }
+    
+    /**
+     * Collect calls deferred earlier in the enclosing function that are still in scope at a line
+     * Scope is approximated by indentation: a closing brace left of a defer ends its block
+     */
+    private findDeferredCalls(lines: string[], line: number): Set<string> {
+        const functionLine = this.findEnclosingFunction(lines, line);
+        let deferred: { call: string; indent: number }[] = [];
+        
+        for (let i = functionLine + 1; i < line; i++) {
+            const indent = lines[i].length - lines[i].trimStart().length;
+            const trimmed = lines[i].trim();
+            
+            if (trimmed.startsWith('}')) {
+                deferred = deferred.filter(d => d.indent <= indent);
+                continue;
+            }
+            
+            const match = trimmed.match(/^defer\s+(.+)$/);
+            if (match && !match[1].startsWith('func')) {
+                deferred.push({ call: this.normalizeStatement(match[1]), indent });
+            }
+        }
+        
+        return new Set(deferred.map(d => d.call));
+    }
+    
+    /**
+     * Normalize a statement for comparison: no trailing comment, semicolon or extra spaces
+     */
+    private normalizeStatement(statement: string): string {
+        return statement
+            .replace(/\s*\/\/.*$/, '')
+            .replace(/;\s*$/, '')
+            .replace(/\s+/g, ' ')
+            .trim();
+    }
+    
+    /**
+     * Check the doc comment directly above a declaration for a directive
+     */
+    private hasDocDirective(lines: string[], declarationLine: number, pattern: RegExp): boolean {
+        for (let i = declarationLine - 1; i >= 0 && lines[i].trim().startsWith('//'); i--) {
+            if (pattern.test(lines[i].trim())) {
+                return true;
+            }
+        }
+        return false;
     }
     
     /**
      * Find the end of an error block
+     * A block with an else clause ends at its "} else" line, which opens the next branch
      */
     private findBlockEnd(
         lines: string[], 
         startIndex: number, 
         expectedIndentation: string
-    ): { endLine: number; bodyLines: string[]; bodyStartLine: number } | null {
+    ): { endLine: number; bodyLines: string[]; bodyStartLine: number; hasElse: boolean } | null {
         let braceCount = 1;
         let j = startIndex + 1;
         const bodyLines: string[] = [];
@@ -156,6 +542,12 @@ export class ErrorBlockDetector {
         while (j < lines.length && braceCount > 0) {
             const currentLine = lines[j];
             
+            // "} else {" closes and reopens on one line, so the count never reaches zero there
+            const elseMatch = braceCount === 1 ? currentLine.match(/^(\s*)\}\s*else\b/) : null;
+            if (elseMatch && elseMatch[1] === expectedIndentation) {
+                return { endLine: j, bodyLines, bodyStartLine, hasElse: true };
+            }
+            
             // Count braces
             const openBraces = (currentLine.match(/\{/g) || []).length;
             const closeBraces = (currentLine.match(/\}/g) || []).length;
@@ -167,12 +559,8 @@ export class ErrorBlockDetector {
                 // Check if closing brace is at expected indentation
                 const closingMatch = currentLine.match(/^(\s*)\}/);
                 if (closingMatch && closingMatch[1] === expectedIndentation) {
-                    // Check for else block - skip these
-                    if (/\}\s*else\s*\{/.test(currentLine) || 
-                        (j + 1 < lines.length && /^\s*else\s*\{/.test(lines[j + 1]))) {
-                        return null; // Skip blocks with else
-                    }
-                    return { endLine: j, bodyLines, bodyStartLine };
+                    const hasElse = j + 1 < lines.length && /^\s*else\b/.test(lines[j + 1]);
+                    return { endLine: j, bodyLines, bodyStartLine, hasElse };
                 }
             }
             
@@ -185,8 +573,13 @@ export class ErrorBlockDetector {
     /**
      * Check if the body is a simple error handling block
      * Allows: single statements, or print/log + return combinations
+     * Returns why the block is not simple, or null if it is
      */
-    private isSimpleErrorReturn(bodyLines: string[]): boolean {
+    private findRejectionReason(
+        bodyLines: string[],
+        customPatterns: RegExp[],
+        deferredCalls: () => Set<string>
+    ): Rejection | null {
         // Filter out empty lines and comment-only lines
         const nonEmpty = bodyLines.filter(line => {
             const trimmed = line.trim();
@@ -195,12 +588,33 @@ export class ErrorBlockDetector {
         
         // Must have at least one statement
         if (nonEmpty.length === 0) {
-            return false;
+            return { reason: 'empty-block', detail: 'empty block' };
         }
         
         // Allow blocks with up to 3 non-empty lines (typical: log/print + return)
         if (nonEmpty.length > 3) {
-            return false;
+            return {
+                reason: 'too-many-statements',
+                detail: `${nonEmpty.length} statements (at most 3 allowed)`,
+            };
+        }
+        
+        // Collecting the error to report later is not an early return
+        const accumulation = nonEmpty.find(line => ErrorBlockDetector.ACCUMULATE_PATTERN.test(line.trim()));
+        if (accumulation) {
+            return { reason: 'accumulates-error', detail: `accumulates the error: ${accumulation.trim()}` };
+        }
+        
+        // Jumps to labels are never hidden, even if a custom pattern matches them
+        const jump = nonEmpty.find(line => /^\s*(goto|break|continue)\s+\w+/.test(line));
+        if (jump) {
+            return { reason: 'label-jump', detail: `jump to label: ${jump.trim()}` };
+        }
+        
+        // break and continue act on the enclosing loop or switch, so the block is not an early return
+        const loopControl = nonEmpty.find(line => /^\s*(break|continue)\s*(;|\/\/|$)/.test(line));
+        if (loopControl) {
+            return { reason: 'loop-control', detail: `loop control: ${loopControl.trim()}` };
         }
         
         // Join lines to handle multi-line statements
@@ -214,6 +628,7 @@ export class ErrorBlockDetector {
             /^panic\s*\(/i,                            // panic(err)
             /^\w+\.(Fatal|Error|Warn|Info|Debug|Print)/i, // custom logger calls
             /^(os\.Exit|syscall\.Exit)/i,             // exit calls
+            ...customPatterns,                         // user-defined statements
         ];
         
         // Check if each non-empty line matches a valid pattern
@@ -228,18 +643,184 @@ export class ErrorBlockDetector {
                 }
             }
             
+            // A cleanup call already covered by an earlier defer is redundant
+            if (!isValid && deferredCalls().has(this.normalizeStatement(trimmed))) {
+                isValid = true;
+            }
+            
             if (!isValid) {
-                return false;
+                return { reason: 'unrecognized-statement', detail: `not an error handling statement: ${trimmed}` };
             }
         }
         
         // Ensure there's at most one return statement
         const returnCount = nonEmpty.filter(l => /^\s*return\b/.test(l)).length;
         if (returnCount > 1) {
+            return { reason: 'multiple-returns', detail: 'more than one return statement' };
+        }
+        
+        return null;
+    }
+    
+    /**
+     * Compile the user's custom statement patterns
+     * Invalid expressions are reported once and skipped
+     */
+    private compileCustomPatterns(): RegExp[] {
+        const patterns: RegExp[] = [];
+        
+        for (const source of ConfigManager.customStatementPatterns) {
+            try {
+                patterns.push(new RegExp(source));
+            } catch (error) {
+                if (!this.reportedInvalidPatterns.has(source)) {
+                    this.reportedInvalidPatterns.add(source);
+                    const message = error instanceof Error ? error.message : String(error);
+                    getOutputChannel().appendLine(`Ignoring invalid customStatementPatterns entry "${source}": ${message}`);
+                }
+            }
+        }
+        
+        return patterns;
+    }
+    
+    /**
+     * Check if the body only returns the checked error unmodified
+     * Other results must be zero values, e.g. "return nil, err" or "return "", err"
+     */
+    private isPassthroughReturn(bodyLines: string[], errorVar: string): boolean {
+        const nonEmpty = bodyLines
+            .map(line => line.trim())
+            .filter(line => line.length > 0 && !line.startsWith('//'));
+        
+        if (nonEmpty.length !== 1) {
+            return false;
+        }
+        
+        const returnMatch = this.normalizeStatement(nonEmpty[0]).match(/^return\s+(.+)$/);
+        if (!returnMatch) {
+            return false;
+        }
+        
+        const results = returnMatch[1].split(',').map(r => r.trim());
+        if (results[results.length - 1] !== errorVar) {
+            return false;
+        }
+        
+        const zeroValuePattern = /^(nil|""|``|0|0\.0|false|[\w.]+\{\})$/;
+        return results.slice(0, -1).every(r => zeroValuePattern.test(r));
+    }
+    
+    /**
+     * Check if the block drops the checked error by returning nil in its place
+     * e.g. "return nil" or "return "", nil" in a function whose last result is an error
+     */
+    public isSwallowedError(lines: string[], check: ErrorCheck): boolean {
+        const nonEmpty = check.bodyLines
+            .map(line => line.trim())
+            .filter(line => line.length > 0 && !line.startsWith('//'));
+        
+        if (nonEmpty.length !== 1) {
+            return false;
+        }
+        
+        const returnMatch = this.normalizeStatement(nonEmpty[0]).match(/^return\s+(.+)$/);
+        if (!returnMatch) {
             return false;
         }
         
-        return true;
+        const results = returnMatch[1].split(',').map(r => r.trim());
+        if (results[results.length - 1] !== 'nil') {
+            return false;
+        }
+        
+        // A different result count means the return belongs to a closure
+        const resultTypes = this.findResultTypes(lines, this.findEnclosingFunction(lines, check.startLine));
+        return resultTypes !== null &&
+            resultTypes.length === results.length &&
+            /(^|\s)error$/.test(resultTypes[resultTypes.length - 1]);
+    }
+    
+    /**
+     * Read the result types of the function enclosing a line
+     * Returns null if the signature cannot be read, or if a function literal
+     * opens earlier in the function, since the line may belong to the closure
+     */
+    public findEnclosingResultTypes(lines: string[], line: number): string[] | null {
+        const functionLine = this.findEnclosingFunction(lines, line);
+        for (let i = functionLine + 1; i < line; i++) {
+            if (/\bfunc\s*\(/.test(lines[i])) {
+                return null;
+            }
+        }
+        return this.findResultTypes(lines, functionLine);
+    }
+    
+    /**
+     * Read the result types of the function declared at a line
+     * Returns null if the signature cannot be read
+     */
+    private findResultTypes(lines: string[], functionLine: number): string[] | null {
+        if (functionLine < 0) {
+            return null;
+        }
+        
+        // Signatures may wrap; they end at the opening brace of the body
+        let signature = '';
+        for (let i = functionLine; i < lines.length && i < functionLine + 20; i++) {
+            signature = `${signature} ${lines[i].replace(/\/\/.*$/, '').trim()}`.trim();
+            if (signature.endsWith('{')) {
+                break;
+            }
+        }
+        if (!signature.endsWith('{')) {
+            return null;
+        }
+        signature = signature.slice(0, -1).trim();
+        
+        let i = 'func'.length;
+        const skipSpaces = (): void => {
+            while (signature[i] === ' ') {
+                i++;
+            }
+        };
+        const skipGroup = (): void => {
+            let depth = 0;
+            do {
+                if ('([{'.includes(signature[i])) {
+                    depth++;
+                } else if (')]}'.includes(signature[i])) {
+                    depth--;
+                }
+                i++;
+            } while (depth > 0 && i < signature.length);
+        };
+        
+        // Skip the receiver, name, type parameters and parameters
+        skipSpaces();
+        if (signature[i] === '(') {
+            skipGroup();
+            skipSpaces();
+        }
+        const name = signature.slice(i).match(/^\w+/);
+        if (!name) {
+            return null;
+        }
+        i += name[0].length;
+        if (signature[i] === '[') {
+            skipGroup();
+        }
+        if (signature[i] !== '(') {
+            return null;
+        }
+        skipGroup();
+        
+        const results = signature.slice(i).trim();
+        if (results.length === 0) {
+            return [];
+        }
+        const list = results.startsWith('(') && results.endsWith(')') ? results.slice(1, -1) : results;
+        return list.split(',').map(r => r.trim());
     }
     
     /**
@@ -271,9 +852,10 @@ export class ErrorBlockDetector {
     /**
      * Generate the collapsed text representation
      */
-    private generateCollapsedText(ifLine: string, bodyStatement: string): string {
+    private generateCollapsedText(ifLine: string, bodyStatement: string, closingComment?: string): string {
         // Extract the condition from the if line
-        const condMatch = ifLine.match(/if\s+(.+?)\s*\{/);
+        // Anchored at the end so a composite literal in the init is kept whole
+        const condMatch = ifLine.match(/if\s+(.+?)\s*\{\s*(\/\/.*)?$/);
         const condition = condMatch ? condMatch[1] : 'err != nil';
         
         // Truncate body if too long
@@ -283,7 +865,8 @@ export class ErrorBlockDetector {
             displayBody = displayBody.substring(0, maxBodyLength) + '...';
         }
         
-        return `if ${condition} { ${displayBody} }`;
+        const suffix = closingComment ? ` ${closingComment}` : '';
+        return `if ${condition} { ${displayBody} }${suffix}`;
     }
     
     /**
@@ -0,0 +1,516 @@
+import * as vscode from 'vscode';
+import { ErrorCheck } from './types';
+import { ConfigManager } from './config';
+import { getDetector, splitLines } from './detector';
+import { isCheckEnabled, reportUnknownChecks } from './checks';
+
+/**
+ * A verb in a fmt.Errorf format string that formats an error without %w
+ */
+export interface UnwrappedErrorfVerb {
+    /** Column range of the verb within the line */
+    start: number;
+    end: number;
+    
+    /** The verb as written, e.g. "%v" */
+    verb: string;
+    
+    /** The error argument it formats */
+    errorVar: string;
+    
+    /** Number of error arguments in the whole call */
+    errorArgs: number;
+    
+    /** Whether the argument is the variable checked by the enclosing error block, not just a likely name */
+    checked: boolean;
+}
+
+/**
+ * Split call arguments at top-level commas, stopping at the closing parenthesis
+ * Returns null if the call does not end on this line
+ */
+function splitCallArguments(text: string): string[] | null {
+    const args: string[] = [];
+    let depth = 0;
+    let current = '';
+    
+    for (let i = 0; i < text.length; i++) {
+        const char = text[i];
+        
+        // Copy string and rune literals whole so their contents are ignored
+        if (char === '"' || char === '`' || char === "'") {
+            const literal = text.slice(i).match(/^("(?:[^"\\]|\\.)*"|`[^`]*`|'(?:[^'\\]|\\.)*')/);
+            if (!literal) {
+                return null;
+            }
+            current += literal[0];
+            i += literal[0].length - 1;
+            continue;
+        }
+        
+        if (char === '(' || char === '[' || char === '{') {
+            depth++;
+        } else if (char === ')' || char === ']' || char === '}') {
+            if (depth === 0) {
+                args.push(current.trim());
+                return args;
+            }
+            depth--;
+        } else if (char === ',' && depth === 0) {
+            args.push(current.trim());
+            current = '';
+            continue;
+        }
+        current += char;
+    }
+    
+    return null;
+}
+
+/**
+ * Find the error variable checked by the error block whose body contains a line
+ */
+export function findCheckedErrorVar(checks: ErrorCheck[], line: number): string | undefined {
+    return checks.find(check => check.bodyStartLine <= line && line < check.endLine)?.errorVar;
+}
+
+/**
+ * Find fmt.Errorf verbs that format an error argument with something other than %w
+ * An argument is an error if it is the checked variable, or its last selector is err, errN,
+ * ends in Err (readErr) or is a sentinel such as ErrNotFound; errMsg, errCode and os.Stderr are not
+ * Calls using explicit argument indexes or * widths are skipped
+ */
+export function findUnwrappedErrorfVerbs(line: string, checkedVar?: string): UnwrappedErrorfVerb[] {
+    const match = line.match(/\bfmt\.Errorf\(\s*"((?:[^"\\]|\\.)*)"\s*,/);
+    if (!match || match.index === undefined) {
+        return [];
+    }
+    
+    const format = match[1];
+    if (/%[^a-zA-Z%]*[[*]/.test(format)) {
+        return [];
+    }
+    
+    const args = splitCallArguments(line.slice(match.index + match[0].length));
+    if (!args) {
+        return [];
+    }
+    
+    const isError = (arg: string | undefined): boolean =>
+        arg !== undefined && (arg === checkedVar || /^(?:\w+\.)*(err\d*|Err(?:[A-Z]\w*)?|\w+Err)$/.test(arg));
+    const errorArgs = args.filter(isError).length;
+    const formatStart = line.indexOf('"', match.index) + 1;
+    const results: UnwrappedErrorfVerb[] = [];
+    
+    let argIndex = 0;
+    for (const verb of format.matchAll(/%[-+# 0-9.]*[a-zA-Z%]/g)) {
+        if (verb[0] === '%%' || verb.index === undefined) {
+            continue;
+        }
+        
+        const arg = args[argIndex++];
+        if (isError(arg) && /[vs]$/.test(verb[0])) {
+            results.push({
+                start: formatStart + verb.index,
+                end: formatStart + verb.index + verb[0].length,
+                verb: verb[0],
+                errorVar: arg,
+                errorArgs,
+                checked: arg === checkedVar,
+            });
+        }
+    }
+    
+    return results;
+}
+
+/**
+ * Reports likely bugs in Go error handling code
+ * Results are published to the Problems panel alongside gopls
+ */
+export class DiagnosticsManager {
+    public static readonly SOURCE = 'Go Error Collapse';
+    private collection: vscode.DiagnosticCollection;
+    
+    constructor() {
+        this.collection = vscode.languages.createDiagnosticCollection('goErrorCollapse');
+    }
+    
+    /**
+     * Recompute diagnostics for a document
+     */
+    public update(document: vscode.TextDocument): void {
+        // Like the providers, only files on disk; git: and diff views would show duplicates
+        if (document.languageId !== 'go' || document.uri.scheme !== 'file') {
+            return;
+        }
+        
+        const text = document.getText();
+        
+        const detector = getDetector();
+        if (!ConfigManager.enableDiagnostics || detector.exceedsMaxFileSize(text) || detector.skipsGeneratedFile(text)) {
+            this.collection.delete(document.uri);
+            return;
+        }
+        
+        this.collection.set(document.uri, this.computeDiagnostics(text));
+    }
+    
+    /**
+     * Run the enabled checks over raw source text
+     * Used for open documents and for workspace scans
+     */
+    public computeDiagnostics(text: string): vscode.Diagnostic[] {
+        reportUnknownChecks();
+        
+        const lines = splitLines(text);
+        const diagnostics: vscode.Diagnostic[] = [];
+        
+        if (isCheckEnabled('log-format')) {
+            lines.forEach((line, index) => {
+                const diagnostic = this.checkLogFormat(line, index);
+                if (diagnostic) {
+                    diagnostics.push(diagnostic);
+                }
+            });
+        }
+        
+        if (isCheckEnabled('errorf-wrap')) {
+            const checks = getDetector().findErrorChecks(text);
+            lines.forEach((line, index) => {
+                diagnostics.push(...this.checkErrorfWrap(line, index, findCheckedErrorVar(checks, index)));
+            });
+        }
+        
+        if (isCheckEnabled('error-var-name')) {
+            lines.forEach((line, index) => {
+                const diagnostic = this.checkErrorVarName(lines, line, index);
+                if (diagnostic) {
+                    diagnostics.push(diagnostic);
+                }
+            });
+        }
+        
+        const unreachable = isCheckEnabled('unreachable');
+        const swallowed = isCheckEnabled('swallowed-error');
+        const stale = isCheckEnabled('stale-error-check');
+        const panics = isCheckEnabled('panic-in-error-func');
+        
+        if (unreachable || swallowed || stale || panics) {
+            const checks = getDetector().findErrorChecks(text);
+            const functions = stale ? getDetector().findFunctions(text) : [];
+            // Last check per function, indentation and variable,
+            // so a check of another variable in between does not hide a stale one
+            const previousChecks: Map<string, ErrorCheck> = new Map();
+            for (const check of checks) {
+                if (unreachable) {
+                    diagnostics.push(...this.checkUnreachable(lines, check));
+                }
+                if (swallowed) {
+                    const diagnostic = this.checkSwallowedError(lines, check);
+                    if (diagnostic) {
+                        diagnostics.push(diagnostic);
+                    }
+                }
+                if (stale) {
+                    const fn = functions.findIndex(f => f.startLine < check.startLine && check.endLine <= f.endLine);
+                    const key = `${fn}\0${check.indentation}\0${check.errorVar}`;
+                    const previous = previousChecks.get(key);
+                    const diagnostic = previous ? this.checkStaleErrorCheck(lines, previous, check) : null;
+                    if (diagnostic) {
+                        diagnostics.push(diagnostic);
+                    }
+                    previousChecks.set(key, check);
+                }
+                if (panics) {
+                    const diagnostic = this.checkPanicInErrorFunc(lines, check);
+                    if (diagnostic) {
+                        diagnostics.push(diagnostic);
+                    }
+                }
+            }
+        }
+        
+        return diagnostics;
+    }
+    
+    /**
+     * Clear diagnostics for a document
+     */
+    public clear(uri: vscode.Uri): void {
+        this.collection.delete(uri);
+    }
+    
+    /**
+     * Flag log.Fatal/log.Print/log.Panic calls that pass a format string
+     * The non-f variants do not interpret verbs like %v
+     */
+    private checkLogFormat(line: string, lineIndex: number): vscode.Diagnostic | null {
+        const match = line.match(/\blog\.(Fatal|Print|Panic)(ln)?\(\s*"((?:[^"\\]|\\.)*)"\s*,/);
+        if (!match || match.index === undefined) {
+            return null;
+        }
+        
+        // Ignore literal percent signs ("%%")
+        const verb = match[3].replace(/%%/g, '').match(/%[-+# 0-9.*]*[a-zA-Z]/);
+        if (!verb) {
+            return null;
+        }
+        
+        const callName = `log.${match[1]}${match[2] || ''}`;
+        const suggestion = `log.${match[1]}f`;
+        const range = new vscode.Range(
+            lineIndex, match.index,
+            lineIndex, match.index + callName.length
+        );
+        
+        const diagnostic = new vscode.Diagnostic(
+            range,
+            `${callName} call has possible formatting directive ${verb[0]}; use ${suggestion}`,
+            vscode.DiagnosticSeverity.Warning
+        );
+        diagnostic.source = DiagnosticsManager.SOURCE;
+        diagnostic.code = 'log-format';
+        
+        return diagnostic;
+    }
+    
+    /**
+     * Flag fmt.Errorf calls that format an error with %v or %s
+     * Only %w keeps the error available to errors.Is and errors.As
+     */
+    private checkErrorfWrap(line: string, lineIndex: number, checkedVar?: string): vscode.Diagnostic[] {
+        return findUnwrappedErrorfVerbs(line, checkedVar).map(found => {
+            const diagnostic = new vscode.Diagnostic(
+                new vscode.Range(lineIndex, found.start, lineIndex, found.end),
+                `fmt.Errorf formats ${found.errorVar} with ${found.verb}; use %w to keep it unwrappable`,
+                vscode.DiagnosticSeverity.Warning
+            );
+            diagnostic.source = DiagnosticsManager.SOURCE;
+            diagnostic.code = 'errorf-wrap';
+            return diagnostic;
+        });
+    }
+    
+    /**
+     * Flag error checks on a variable named e, er or error instead of err
+     * Only flagged when the variable is clearly an error: the last of several
+     * results assigned from a call, or named error (which shadows the builtin)
+     */
+    private checkErrorVarName(lines: string[], line: string, lineIndex: number): vscode.Diagnostic | null {
+        const match = line.match(/^\s*if\s+(?:(.*);\s*)?\b(e|er|error)\s*!=\s*nil\s*\{/);
+        if (!match) {
+            return null;
+        }
+        
+        const name = match[2];
+        if (name !== 'error') {
+            // The assignment is either the if statement's init or the previous line
+            const assignment = match[1] ?? (lineIndex > 0 ? lines[lineIndex - 1] : '');
+            const lhs = assignment.match(/^\s*([\w\s,]+?)\s*:?=\s*\S.*\(/);
+            const names = lhs ? lhs[1].split(',').map(n => n.trim()) : [];
+            if (names.length < 2 || names[names.length - 1] !== name) {
+                return null;
+            }
+        }
+        
+        const start = line.search(new RegExp(`\\b${name}\\s*!=\\s*nil`));
+        const message = name === 'error'
+            ? 'Error variable named error shadows the built-in error type; name it err'
+            : `Error variable ${name} should be named err`;
+        const diagnostic = new vscode.Diagnostic(
+            new vscode.Range(lineIndex, start, lineIndex, start + name.length),
+            message,
+            vscode.DiagnosticSeverity.Information
+        );
+        diagnostic.source = DiagnosticsManager.SOURCE;
+        diagnostic.code = 'error-var-name';
+        
+        return diagnostic;
+    }
+    
+    /**
+     * Flag error blocks that drop the error: an empty block, or only returning nil in its place
+     * No quick fix is offered; whether to return, wrap or log the error is up to the author
+     */
+    private checkSwallowedError(lines: string[], check: ErrorCheck): vscode.Diagnostic | null {
+        const isEmpty = check.bodyLines.every(line => line.trim().length === 0);
+        if (!isEmpty && !getDetector().isSwallowedError(lines, check)) {
+            return null;
+        }
+        
+        const message = isEmpty
+            ? `${check.errorVar} is checked but the block is empty`
+            : `${check.errorVar} is checked but dropped: the block returns nil in its place`;
+        const diagnostic = new vscode.Diagnostic(
+            new vscode.Range(
+                check.startLine, check.indentation.length,
+                check.startLine, lines[check.startLine].length
+            ),
+            message,
+            vscode.DiagnosticSeverity.Warning
+        );
+        diagnostic.source = DiagnosticsManager.SOURCE;
+        diagnostic.code = 'swallowed-error';
+        
+        return diagnostic;
+    }
+    
+    /**
+     * Flag error blocks that only panic with the error in a function whose last result is error
+     * main, init and tests have no error result and are never flagged
+     */
+    private checkPanicInErrorFunc(lines: string[], check: ErrorCheck): vscode.Diagnostic | null {
+        const statements = check.bodyLines
+            .map((text, offset) => ({ text, line: check.bodyStartLine + offset }))
+            .filter(({ text }) => text.trim().length > 0 && !text.trim().startsWith('//'));
+        const call = `panic(${check.errorVar})`;
+        
+        if (statements.length !== 1 || statements[0].text.trim().replace(/\s+/g, '') !== call) {
+            return null;
+        }
+        
+        const resultTypes = getDetector().findEnclosingResultTypes(lines, check.startLine);
+        if (!resultTypes || !/(^|\s)error$/.test(resultTypes[resultTypes.length - 1])) {
+            return null;
+        }
+        
+        const { text, line } = statements[0];
+        const start = text.length - text.trimStart().length;
+        const diagnostic = new vscode.Diagnostic(
+            new vscode.Range(line, start, line, text.trimEnd().length),
+            `${call} in a function that returns an error; return ${check.errorVar} instead`,
+            vscode.DiagnosticSeverity.Information
+        );
+        diagnostic.source = DiagnosticsManager.SOURCE;
+        diagnostic.code = 'panic-in-error-func';
+        
+        return diagnostic;
+    }
+    
+    /**
+     * Flag an error check whose variable was not assigned since the previous check of it
+     * Both checks must be in the same block: every line between them is at least as
+     * deeply indented, so else branches, switch cases and function boundaries break the pair
+     */
+    private checkStaleErrorCheck(
+        lines: string[],
+        previous: ErrorCheck,
+        check: ErrorCheck
+    ): vscode.Diagnostic | null {
+        if (previous.errorVar !== check.errorVar || previous.indentation !== check.indentation) {
+            return null;
+        }
+        
+        const name = check.errorVar.replace(/[.*+?^${}()|[\]\\]/g, '\\$&');
+        const assignment = new RegExp(
+            `(^|[^\\w.])${name}(\\s*,\\s*[\\w.]+)*\\s*:?=(?!=)|&${name}\\b|\\bvar\\s+${name}\\b`
+        );
+        
+        // The check's own line is included for an "if err := f(); err != nil" init
+        for (let line = previous.bodyStartLine; line <= check.startLine; line++) {
+            const text = lines[line];
+            const code = text.replace(/"(?:[^"\\]|\\.)*"|`[^`]*`|\/\/.*$/g, '');
+            if (assignment.test(code)) {
+                return null;
+            }
+            if (line > previous.endLine && code.trim().length > 0 &&
+                text.length - text.trimStart().length < check.indentation.length) {
+                return null;
+            }
+        }
+        
+        const start = lines[check.startLine].indexOf(check.errorVar, check.indentation.length);
+        const diagnostic = new vscode.Diagnostic(
+            new vscode.Range(check.startLine, start, check.startLine, start + check.errorVar.length),
+            `${check.errorVar} is checked again but not assigned since the check on line ${previous.startLine + 1}`,
+            vscode.DiagnosticSeverity.Warning
+        );
+        diagnostic.source = DiagnosticsManager.SOURCE;
+        diagnostic.code = 'stale-error-check';
+        
+        return diagnostic;
+    }
+    
+    /**
+     * Flag statements after log.Fatal/os.Exit/panic in an error block
+     * Only direct statements of the block body are considered; the scan stops at the
+     * first "}" at the if statement's indentation, so an else branch is never reported
+     */
+    private checkUnreachable(lines: string[], check: ErrorCheck): vscode.Diagnostic[] {
+        const terminatingCall = /^(log\.(?:Fatal|Panic)(?:f|ln)?|os\.Exit|panic)\s*\(/;
+        const diagnostics: vscode.Diagnostic[] = [];
+        let terminatedBy: string | null = null;
+        let depth = 0;
+        
+        for (let line = check.bodyStartLine; line < check.endLine; line++) {
+            const text = lines[line];
+            const trimmed = text.trim();
+            const depthBefore = depth;
+            
+            if (trimmed.startsWith('}') && text.length - text.trimStart().length <= check.indentation.length) {
+                break;
+            }
+            
+            // Track brackets so continuation lines and nested blocks are skipped
+            const code = trimmed.replace(/"(?:[^"\\]|\\.)*"|`[^`]*`|'(?:[^'\\]|\\.)*'|\/\/.*$/g, '');
+            depth += (code.match(/[({[]/g) || []).length - (code.match(/[)}\]]/g) || []).length;
+            
+            if (depthBefore !== 0 || trimmed.length === 0 || trimmed.startsWith('//')) {
+                continue;
+            }
+            
+            if (terminatedBy) {
+                const start = text.length - text.trimStart().length;
+                const diagnostic = new vscode.Diagnostic(
+                    new vscode.Range(line, start, line, text.length),
+                    `Unreachable code: ${terminatedBy} does not return`,
+                    vscode.DiagnosticSeverity.Hint
+                );
+                diagnostic.source = DiagnosticsManager.SOURCE;
+                diagnostic.code = 'unreachable';
+                diagnostic.tags = [vscode.DiagnosticTag.Unnecessary];
+                diagnostics.push(diagnostic);
+                continue;
+            }
+            
+            const match = trimmed.match(terminatingCall);
+            if (match) {
+                terminatedBy = match[1];
+            }
+        }
+        
+        return diagnostics;
+    }
+    
+    /**
+     * Dispose all resources
+     */
+    public dispose(): void {
+        this.collection.clear();
+        this.collection.dispose();
+    }
+}
+
+// Singleton instance
+let diagnosticsManagerInstance: DiagnosticsManager | null = null;
+
+/**
+ * Get the shared diagnostics manager instance
+ */
+export function getDiagnosticsManager(): DiagnosticsManager {
+    if (!diagnosticsManagerInstance) {
+        diagnosticsManagerInstance = new DiagnosticsManager();
+    }
+    return diagnosticsManagerInstance;
+}
+
+/**
+ * Dispose the shared diagnostics manager
+ */
+export function disposeDiagnosticsManager(): void {
+    if (diagnosticsManagerInstance) {
+        diagnosticsManagerInstance.dispose();
+        diagnosticsManagerInstance = null;
+    }
+}
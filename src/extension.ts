@@ -1,9 +1,16 @@
 import * as vscode from 'vscode';
 import { registerFoldingProvider } from './foldingProvider';
+import { registerCodeActionProvider, renameErrorVariable } from './codeActionProvider';
 import { getDetector, disposeDetector } from './detector';
 import { getDecorationManager, disposeDecorationManager } from './decorationManager';
+import { getDiagnosticsManager, disposeDiagnosticsManager } from './diagnostics';
+import { disposeOutputChannel } from './outputChannel';
+import { showStatistics, countHiddenLines } from './statistics';
+import { exportFindings, showFindings, showNearMisses } from './findings';
+import { createApi } from './api';
+import { listChecks, explainCheck } from './checks';
 import { ConfigManager } from './config';
-import { CollapseState } from './types';
+import { CollapseState, GoErrorCollapseApi } from './types';
 
 // Track collapse state per document
 const documentStates: Map<string, CollapseState> = new Map();
@@ -66,6 +73,14 @@ async function collapseAllErrorBlocksSilent(editor: vscode.TextEditor): Promise<
     return errorBlocks.length;
 }
 
+/**
+ * Build the notification shown after collapsing
+ */
+function formatCollapseMessage(document: vscode.TextDocument, count: number): string {
+    const hiddenLines = countHiddenLines(getDetector().detectErrorBlocks(document));
+    return `Go Error Collapse: Collapsed ${count} error block(s), hiding ${hiddenLines} line(s)`;
+}
+
 /**
  * Collapse all error blocks in the active editor
  */
@@ -88,9 +103,7 @@ async function collapseAllErrorBlocks(editor: vscode.TextEditor): Promise<void>
     if (count === 0) {
         vscode.window.showInformationMessage('Go Error Collapse: No error blocks found');
     } else {
-        vscode.window.showInformationMessage(
-            `Go Error Collapse: Collapsed ${count} error block(s)`
-        );
+        vscode.window.showInformationMessage(formatCollapseMessage(document, count));
     }
 }
 
@@ -182,11 +195,117 @@ async function toggleErrorBlocks(editor: vscode.TextEditor): Promise<void> {
         if (count === 0) {
             vscode.window.showInformationMessage('Go Error Collapse: No error blocks found');
         } else {
-            vscode.window.showInformationMessage(`Go Error Collapse: Collapsed ${count} error block(s)`);
+            vscode.window.showInformationMessage(formatCollapseMessage(editor.document, count));
         }
     }
 }
 
+/**
+ * Toggle only the error block containing the cursor
+ */
+async function toggleErrorBlockAtCursor(editor: vscode.TextEditor): Promise<void> {
+    const document = editor.document;
+    
+    if (document.languageId !== 'go') {
+        vscode.window.showInformationMessage('Go Error Collapse: Not a Go file');
+        return;
+    }
+    
+    // Match by block extent so any line of the block works, including the closing brace
+    const line = editor.selection.active.line;
+    const block = getDetector().detectErrorBlocks(document)
+        .find(b => b.startLine <= line && line <= b.endLine);
+    
+    if (!block) {
+        vscode.window.showInformationMessage('Go Error Collapse: No collapsible error block at the cursor');
+        return;
+    }
+    
+    // The API does not expose fold state, but a folded block's body is missing from the visible ranges;
+    // the cursor can only be inside the block's lines when the block is unfolded
+    const folded = line === block.startLine && !editor.visibleRanges.some(range =>
+        range.start.line <= block.bodyStartLine && block.bodyStartLine <= range.end.line
+    );
+    
+    // Keep the cursor on the line that stays visible
+    const ifLineEnd = document.lineAt(block.startLine).range.end;
+    editor.selection = new vscode.Selection(ifLineEnd, ifLineEnd);
+    await vscode.commands.executeCommand(folded ? 'editor.unfold' : 'editor.fold', {
+        levels: 1,
+        direction: 'down',
+        selectionLines: [block.startLine]
+    });
+    
+    // Hints follow the fold that was performed
+    const decorations = getDecorationManager();
+    if (folded) {
+        decorations.removeCollapsedHint(document.uri.toString(), block.startLine);
+    } else {
+        decorations.addCollapsedHints(editor, [block]);
+        getDocumentState(document.uri.toString()).isCollapsed = true;
+    }
+}
+
+/**
+ * Collapse the error blocks of the functions picked by name
+ * The function containing the cursor is preselected
+ */
+async function collapseInFunctions(editor: vscode.TextEditor): Promise<void> {
+    const document = editor.document;
+    
+    if (document.languageId !== 'go') {
+        vscode.window.showInformationMessage('Go Error Collapse: Not a Go file');
+        return;
+    }
+    
+    const functions = getDetector().findFunctions(document.getText());
+    if (functions.length === 0) {
+        vscode.window.showInformationMessage('Go Error Collapse: No functions found');
+        return;
+    }
+    
+    const line = editor.selection.active.line;
+    const picked = await vscode.window.showQuickPick(
+        functions.map(fn => ({
+            label: fn.name,
+            description: `line ${fn.startLine + 1}`,
+            picked: fn.startLine <= line && line <= fn.endLine,
+            fn,
+        })),
+        { canPickMany: true, placeHolder: 'Select the functions whose error blocks to collapse' }
+    );
+    
+    if (!picked || picked.length === 0) {
+        return;
+    }
+    
+    const blocks = getDetector().detectErrorBlocks(document).filter(block =>
+        picked.some(({ fn }) => fn.startLine < block.startLine && block.endLine <= fn.endLine)
+    );
+    
+    if (blocks.length === 0) {
+        vscode.window.showInformationMessage('Go Error Collapse: No error blocks found in the selected functions');
+        return;
+    }
+    
+    const originalSelection = editor.selection;
+    for (const block of blocks) {
+        await vscode.commands.executeCommand('editor.fold', {
+            levels: 1,
+            direction: 'down',
+            selectionLines: [block.startLine]
+        });
+    }
+    editor.selection = originalSelection;
+    
+    getDocumentState(document.uri.toString()).isCollapsed = true;
+    getDecorationManager().addCollapsedHints(editor, blocks);
+    
+    vscode.window.showInformationMessage(
+        `Go Error Collapse: Collapsed ${blocks.length} error block(s) in ${picked.map(p => p.label).join(', ')}`
+    );
+}
+
 /**
  * Make error blocks transparent
  */
@@ -228,6 +347,11 @@ async function autoCollapseOnOpen(editor: vscode.TextEditor): Promise<void> {
     // Small delay to ensure the document is fully loaded
     await new Promise(resolve => setTimeout(resolve, 100));
     
+    // Leave files with only a few one-off blocks untouched
+    if (getDetector().detectErrorBlocks(editor.document).length < ConfigManager.minBlocks) {
+        return;
+    }
+    
     await collapseAllErrorBlocks(editor);
 }
 
@@ -271,6 +395,13 @@ async function autoCollapseOnSave(document: vscode.TextDocument): Promise<void>
  * Handle configuration changes
  */
 function onConfigurationChange(): void {
+    // Cached blocks were detected under the old settings
+    getDetector().clearAllCaches();
+    
+    // Recompute diagnostics for every open Go document
+    const diagnosticsManager = getDiagnosticsManager();
+    vscode.workspace.textDocuments.forEach(document => diagnosticsManager.update(document));
+    
     const editor = vscode.window.activeTextEditor;
     if (!editor || editor.document.languageId !== 'go') {
         return;
@@ -286,15 +417,43 @@ function onConfigurationChange(): void {
     }
 }
 
+/**
+ * Re-run detection after an edit and refresh diagnostics and transparency
+ * Debounced so a burst of keystrokes triggers a single scan
+ */
+function onDocumentEdited(document: vscode.TextDocument): void {
+    if (document.languageId !== 'go' || document.uri.scheme !== 'file') {
+        return;
+    }
+    
+    getDetector().detectDebounced(document, () => {
+        getDiagnosticsManager().update(document);
+        
+        const state = documentStates.get(document.uri.toString());
+        const editor = vscode.window.visibleTextEditors.find(e => e.document === document);
+        
+        // Blocks may have been added or removed; hint decorations move with the text
+        if (state?.isTransparent && editor) {
+            const decorationManager = getDecorationManager();
+            decorationManager.removeTransparency(editor);
+            decorationManager.applyTransparency(editor);
+        }
+    });
+}
+
 /**
  * Extension activation
+ * Returns the public API for other extensions
  */
-export function activate(context: vscode.ExtensionContext): void {
+export function activate(context: vscode.ExtensionContext): GoErrorCollapseApi {
     console.log('Go Error Collapse extension is activating...');
     
     // Register folding provider
     registerFoldingProvider(context);
     
+    // Register quick fixes for diagnostics
+    registerCodeActionProvider(context);
+    
     // Register commands
     const collapseAllCommand = vscode.commands.registerCommand(
         'goErrorCollapse.collapseAll',
@@ -326,6 +485,32 @@ export function activate(context: vscode.ExtensionContext): void {
         }
     );
     
+    const toggleAtCursorCommand = vscode.commands.registerCommand(
+        'goErrorCollapse.toggleAtCursor',
+        () => {
+            const editor = vscode.window.activeTextEditor;
+            if (editor) {
+                toggleErrorBlockAtCursor(editor);
+            }
+        }
+    );
+    
+    const collapseInFunctionCommand = vscode.commands.registerCommand(
+        'goErrorCollapse.collapseInFunction',
+        () => {
+            const editor = vscode.window.activeTextEditor;
+            if (editor) {
+                collapseInFunctions(editor);
+            }
+        }
+    );
+    
+    // Internal: invoked by the error-var-name quick fix
+    const renameErrorVariableCommand = vscode.commands.registerCommand(
+        'goErrorCollapse.renameErrorVariable',
+        (uri: vscode.Uri, position: vscode.Position) => renameErrorVariable(uri, position)
+    );
+    
     const makeTransparentCommand = vscode.commands.registerCommand(
         'goErrorCollapse.makeTransparent',
         () => {
@@ -346,6 +531,51 @@ export function activate(context: vscode.ExtensionContext): void {
         }
     );
     
+    const showStatisticsCommand = vscode.commands.registerCommand(
+        'goErrorCollapse.showStatistics',
+        () => showStatistics()
+    );
+    
+    const exportFindingsCommand = vscode.commands.registerCommand(
+        'goErrorCollapse.exportFindings',
+        () => {
+            const editor = vscode.window.activeTextEditor;
+            if (editor) {
+                exportFindings(editor);
+            }
+        }
+    );
+    
+    const showFindingsCommand = vscode.commands.registerCommand(
+        'goErrorCollapse.showFindings',
+        () => {
+            const editor = vscode.window.activeTextEditor;
+            if (editor) {
+                showFindings(editor);
+            }
+        }
+    );
+    
+    const showNearMissesCommand = vscode.commands.registerCommand(
+        'goErrorCollapse.showNearMisses',
+        () => {
+            const editor = vscode.window.activeTextEditor;
+            if (editor) {
+                showNearMisses(editor);
+            }
+        }
+    );
+    
+    const listChecksCommand = vscode.commands.registerCommand(
+        'goErrorCollapse.listChecks',
+        () => listChecks()
+    );
+    
+    const explainCheckCommand = vscode.commands.registerCommand(
+        'goErrorCollapse.explainCheck',
+        () => explainCheck()
+    );
+    
     // Register event listeners
     const onActiveEditorChange = vscode.window.onDidChangeActiveTextEditor(editor => {
         if (editor) {
@@ -357,6 +587,14 @@ export function activate(context: vscode.ExtensionContext): void {
         autoCollapseOnSave(document);
     });
     
+    const onDidOpenTextDocument = vscode.workspace.onDidOpenTextDocument(document => {
+        getDiagnosticsManager().update(document);
+    });
+    
+    const onDidChangeTextDocument = vscode.workspace.onDidChangeTextDocument(event => {
+        onDocumentEdited(event.document);
+    });
+    
     const onConfigChange = ConfigManager.onConfigurationChange(onConfigurationChange);
     
     // Handle document close - clean up state
@@ -365,6 +603,7 @@ export function activate(context: vscode.ExtensionContext): void {
         documentStates.delete(uri);
         getDecorationManager().clearHints(uri);
         getDetector().invalidateCache(document);
+        getDiagnosticsManager().clear(document.uri);
     });
     
     // Add all disposables
@@ -372,14 +611,28 @@ export function activate(context: vscode.ExtensionContext): void {
         collapseAllCommand,
         expandAllCommand,
         toggleCommand,
+        toggleAtCursorCommand,
+        collapseInFunctionCommand,
+        renameErrorVariableCommand,
         makeTransparentCommand,
         resetTransparencyCommand,
+        showStatisticsCommand,
+        exportFindingsCommand,
+        showFindingsCommand,
+        showNearMissesCommand,
+        listChecksCommand,
+        explainCheckCommand,
         onActiveEditorChange,
         onDidSaveTextDocument,
+        onDidOpenTextDocument,
+        onDidChangeTextDocument,
         onConfigChange,
         onDidCloseTextDocument
     );
     
+    // Report diagnostics for documents that were open before activation
+    vscode.workspace.textDocuments.forEach(document => getDiagnosticsManager().update(document));
+    
     // Auto-collapse if a Go file is already open
     const activeEditor = vscode.window.activeTextEditor;
     if (activeEditor && activeEditor.document.languageId === 'go') {
@@ -388,6 +641,8 @@ export function activate(context: vscode.ExtensionContext): void {
     }
     
     console.log('Go Error Collapse extension is now active!');
+    
+    return createApi();
 }
 
 /**
@@ -399,6 +654,8 @@ export function deactivate(): void {
     // Clean up resources
     disposeDetector();
     disposeDecorationManager();
+    disposeDiagnosticsManager();
+    disposeOutputChannel();
     documentStates.clear();
     
     console.log('Go Error Collapse extension deactivated');
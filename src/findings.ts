@@ -0,0 +1,260 @@
+import * as vscode from 'vscode';
+import { createHash } from 'crypto';
+import { ErrorBlock, Finding, SkippedBlock, SkippedFinding, SkipReason } from './types';
+import { getDetector } from './detector';
+import { getOutputChannel } from './outputChannel';
+import { countChecks } from './statistics';
+
+/**
+ * Version of the exported JSON format
+ * Bump when fields are renamed or removed
+ */
+export const FINDINGS_FORMAT_VERSION = 1;
+
+// Human-readable messages per finding kind
+const KIND_MESSAGES: Record<string, string> = {
+    'collapsible-error-block': 'collapsible error block',
+};
+
+// Skip reasons for blocks that are simple error handling kept expanded by a setting
+// or by a single unrecognized statement; a pattern or setting change would collapse them
+const NEAR_MISS_REASONS: ReadonlySet<SkipReason> = new Set<SkipReason>([
+    'unrecognized-statement',
+    'not-passthrough',
+    'not-opted-in',
+]);
+
+/**
+ * Convert a UTF-16 document offset into a UTF-8 byte offset
+ */
+function toByteOffset(text: string, offset: number): number {
+    return Buffer.byteLength(text.substring(0, offset), 'utf8');
+}
+
+/**
+ * Split a line into its code and trailing "//" comment, ignoring "//" inside literals
+ */
+function splitComment(line: string): { code: string; comment?: string } {
+    const match = line.match(/^((?:"(?:[^"\\]|\\.)*"|`[^`]*`|'(?:[^'\\]|\\.)*'|[^"`'/]|\/(?!\/))*)(\/\/.*)?$/);
+    if (!match) {
+        return { code: line.trim() };
+    }
+    return { code: match[1].trim(), comment: match[2]?.trim() };
+}
+
+/**
+ * Build a valid single-line Go form of an error block
+ * Unlike collapsedText this is never truncated; comments in the block are kept after it
+ */
+function buildOneLiner(document: vscode.TextDocument, block: ErrorBlock): string {
+    const ifLine = document.lineAt(block.startLine).text;
+    const condMatch = ifLine.match(/if\s+(.+?)\s*\{\s*(\/\/.*)?$/);
+    const condition = condMatch ? condMatch[1] : 'err != nil';
+    
+    const comments: string[] = condMatch?.[2] ? [condMatch[2].trim()] : [];
+    const statements: string[] = [];
+    for (let line = block.bodyStartLine; line < block.endLine; line++) {
+        const { code, comment } = splitComment(document.lineAt(line).text);
+        if (code.length > 0) {
+            statements.push(code);
+        }
+        if (comment) {
+            comments.push(comment);
+        }
+    }
+    if (block.closingComment) {
+        comments.push(block.closingComment);
+    }
+    
+    const suffix = comments.length > 0 ? ` ${comments.join(' ')}` : '';
+    return `if ${condition} { ${statements.join('; ')} }${suffix}`;
+}
+
+/**
+ * Find the trimmed statement above a block that produced its error, or '' if there is none
+ * Blocks with an init statement ("if err := f(); err != nil") already contain it
+ */
+function findProducer(document: vscode.TextDocument, block: ErrorBlock, functionStart: number): string {
+    if (/^\s*if\s+[^;{]+;/.test(document.lineAt(block.startLine).text)) {
+        return '';
+    }
+    
+    for (let line = block.startLine - 1; line > functionStart; line--) {
+        const trimmed = document.lineAt(line).text.trim();
+        if (trimmed.length === 0 || trimmed.startsWith('//')) {
+            continue;
+        }
+        return trimmed.startsWith('}') ? '' : trimmed;
+    }
+    return '';
+}
+
+/**
+ * Build a fingerprint for a block from its file, enclosing function and trimmed text
+ * Identical blocks after the same statement in one function are told apart by their order of appearance
+ */
+function buildFingerprint(file: string, functionName: string, blockText: string, occurrence: number): string {
+    return createHash('sha1')
+        .update([file, functionName, blockText, String(occurrence)].join('\0'))
+        .digest('hex')
+        .slice(0, 16);
+}
+
+/**
+ * Build findings for the detected error blocks of a document
+ */
+export function buildFindings(document: vscode.TextDocument, blocks: ErrorBlock[]): Finding[] {
+    const text = document.getText();
+    const file = vscode.workspace.asRelativePath(document.uri);
+    const functions = getDetector().findFunctions(text);
+    const occurrences: Map<string, number> = new Map();
+    
+    return blocks.map(block => {
+        // Blank lines and indentation are left out so reformatting keeps the fingerprint
+        const fn = functions.find(f => f.startLine < block.startLine && block.endLine <= f.endLine);
+        const blockText: string[] = [];
+        for (let line = block.startLine; line <= block.endLine; line++) {
+            const trimmed = document.lineAt(line).text.trim();
+            if (trimmed.length > 0) {
+                blockText.push(trimmed);
+            }
+        }
+        // Identical boilerplate blocks are told apart by the statement that produced the error,
+        // so only inserting the same statement and block shifts the occurrence index
+        const producer = findProducer(document, block, fn?.startLine ?? -1);
+        const shape = `${fn?.name ?? ''}\0${producer}\0${blockText.join('\n')}`;
+        const occurrence = occurrences.get(shape) ?? 0;
+        occurrences.set(shape, occurrence + 1);
+        
+        // Span from the "if" keyword to the end of the closing line
+        const start = new vscode.Position(block.startLine, block.indentation.length);
+        const end = block.fullRange.end;
+        
+        return {
+            file,
+            startLine: start.line + 1,
+            startCol: start.character + 1,
+            endLine: end.line + 1,
+            endCol: end.character + 1,
+            kind: 'collapsible-error-block',
+            suggestedEdit: {
+                startOffset: toByteOffset(text, document.offsetAt(start)),
+                endOffset: toByteOffset(text, document.offsetAt(end)),
+                newText: buildOneLiner(document, block),
+            },
+            fingerprint: buildFingerprint(file, fn?.name ?? '', `${producer}\n${blockText.join('\n')}`, occurrence),
+        };
+    });
+}
+
+/**
+ * Build entries for error blocks that were found but not collapsed
+ */
+export function buildSkippedFindings(
+    document: vscode.TextDocument,
+    skipped: SkippedBlock[]
+): SkippedFinding[] {
+    const file = vscode.workspace.asRelativePath(document.uri);
+    
+    return skipped.map(block => ({
+        file,
+        startLine: block.startLine + 1,
+        startCol: block.indentation.length + 1,
+        endLine: block.endLine + 1,
+        endCol: document.lineAt(block.endLine).text.length + 1,
+        reason: block.reason,
+        detail: block.detail,
+    }));
+}
+
+/**
+ * Format a finding as a single "path:line:col: message" line
+ * Keep this stable: editors and scripts match on the prefix
+ */
+export function formatFinding(finding: Finding): string {
+    const message = KIND_MESSAGES[finding.kind] || finding.kind;
+    return `${finding.file}:${finding.startLine}:${finding.startCol}: ${message} (kind=${finding.kind})`;
+}
+
+/**
+ * Print the findings for the active Go file to the output channel, one per line
+ */
+export function showFindings(editor: vscode.TextEditor): void {
+    const document = editor.document;
+    
+    if (document.languageId !== 'go') {
+        vscode.window.showInformationMessage('Go Error Collapse: Not a Go file');
+        return;
+    }
+    
+    const blocks = getDetector().detectErrorBlocks(document);
+    const output = getOutputChannel();
+    
+    output.clear();
+    for (const finding of buildFindings(document, blocks)) {
+        output.appendLine(formatFinding(finding));
+    }
+    output.show(true);
+}
+
+/**
+ * Print the near misses for the active Go file to the output channel, one per line
+ * These are skipped blocks that would collapse with a settings change
+ */
+export function showNearMisses(editor: vscode.TextEditor): void {
+    const document = editor.document;
+    
+    if (document.languageId !== 'go') {
+        vscode.window.showInformationMessage('Go Error Collapse: Not a Go file');
+        return;
+    }
+    
+    const { skipped } = getDetector().analyzeText(
+        document.getText(),
+        vscode.workspace.asRelativePath(document.uri)
+    );
+    const nearMisses = buildSkippedFindings(document, skipped)
+        .filter(finding => NEAR_MISS_REASONS.has(finding.reason));
+    const output = getOutputChannel();
+    
+    output.clear();
+    for (const finding of nearMisses) {
+        output.appendLine(
+            `${finding.file}:${finding.startLine}:${finding.startCol}: near miss (reason=${finding.reason}): ${finding.detail}`
+        );
+    }
+    if (nearMisses.length === 0) {
+        output.appendLine('No near misses');
+    }
+    output.show(true);
+}
+
+/**
+ * Open the findings for the active Go file as a JSON document
+ */
+export async function exportFindings(editor: vscode.TextEditor): Promise<void> {
+    const document = editor.document;
+    
+    if (document.languageId !== 'go') {
+        vscode.window.showInformationMessage('Go Error Collapse: Not a Go file');
+        return;
+    }
+    
+    const { blocks, skipped } = getDetector().analyzeText(
+        document.getText(),
+        vscode.workspace.asRelativePath(document.uri)
+    );
+    const report = {
+        version: FINDINGS_FORMAT_VERSION,
+        findings: buildFindings(document, blocks),
+        skipped: buildSkippedFindings(document, skipped),
+        patterns: getDetector().countPatterns(document.getText()),
+        byRule: countChecks(document.getText()),
+    };
+    
+    const jsonDocument = await vscode.workspace.openTextDocument({
+        language: 'json',
+        content: JSON.stringify(report, null, 2),
+    });
+    await vscode.window.showTextDocument(jsonDocument, { preview: false });
+}
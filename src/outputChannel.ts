@@ -0,0 +1,24 @@
+import * as vscode from 'vscode';
+
+// Singleton instance
+let outputChannelInstance: vscode.OutputChannel | null = null;
+
+/**
+ * Get the shared "Go Error Collapse" output channel
+ */
+export function getOutputChannel(): vscode.OutputChannel {
+    if (!outputChannelInstance) {
+        outputChannelInstance = vscode.window.createOutputChannel('Go Error Collapse');
+    }
+    return outputChannelInstance;
+}
+
+/**
+ * Dispose the shared output channel
+ */
+export function disposeOutputChannel(): void {
+    if (outputChannelInstance) {
+        outputChannelInstance.dispose();
+        outputChannelInstance = null;
+    }
+}
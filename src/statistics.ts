@@ -0,0 +1,312 @@
+import * as vscode from 'vscode';
+import { createHash } from 'crypto';
+import { ErrorBlock, FileStatistics, ErrorHandlingPattern, PatternCounts, CheckCounts } from './types';
+import { getDetector } from './detector';
+import { getDiagnosticsManager } from './diagnostics';
+import { getOutputChannel } from './outputChannel';
+import { ConfigManager } from './config';
+
+// Number of files read concurrently during workspace scans
+const SCAN_CONCURRENCY = 16;
+
+// Maximum number of remembered scan results before the cache is reset
+const SCAN_CACHE_LIMIT = 10000;
+
+// Block counts from earlier scans, keyed by file content and settings
+const scanCache: Map<string, Omit<FileStatistics, 'path'>> = new Map();
+
+// Scan phases timed when goErrorCollapse.trace is on
+type ScanPhase = 'read' | 'detect' | 'patterns' | 'checks';
+
+/**
+ * Run a step and add its duration to the phase total
+ */
+function timed<T>(times: Record<ScanPhase, number>, phase: ScanPhase, step: () => T): T {
+    const start = performance.now();
+    try {
+        return step();
+    } finally {
+        times[phase] += performance.now() - start;
+    }
+}
+
+/**
+ * Print the time spent per scan phase, summed across files
+ * Reads overlap, so their total can exceed the wall-clock time
+ */
+function printPhaseTimes(times: Record<ScanPhase, number>, cached: number): void {
+    const output = getOutputChannel();
+    output.appendLine('');
+    output.appendLine(`${'Phase'.padEnd(8)}  ${'ms'.padStart(8)}`);
+    for (const [phase, ms] of Object.entries(times)) {
+        output.appendLine(`${phase.padEnd(8)}  ${ms.toFixed(1).padStart(8)}`);
+    }
+    output.appendLine(`${cached} file(s) reused from an earlier scan`);
+}
+
+/**
+ * Build the scan cache key for a file's text
+ * Settings are part of the key so changing them invalidates earlier results
+ */
+function scanCacheKey(text: string): string {
+    return createHash('sha1')
+        .update(JSON.stringify(ConfigManager.getConfig()))
+        .update('\0')
+        .update(text)
+        .digest('hex');
+}
+
+/**
+ * Count the lines hidden when the given blocks are folded
+ * The "if err != nil {" line itself stays visible
+ */
+export function countHiddenLines(blocks: ErrorBlock[]): number {
+    return blocks.reduce((sum, block) => sum + block.endLine - block.startLine, 0);
+}
+
+/**
+ * Build the exclude glob for workspace scans from the exclude setting
+ * An exclude passed to findFiles replaces files.exclude, so the user's files.exclude
+ * and search.exclude globs are merged in, as a workspace search would apply them
+ */
+function buildExcludePattern(): string | undefined {
+    const patterns = [...ConfigManager.exclude];
+    for (const section of ['files', 'search']) {
+        const globs = vscode.workspace.getConfiguration(section).get<Record<string, unknown>>('exclude', {});
+        for (const [glob, enabled] of Object.entries(globs)) {
+            // Conditional entries ({ "when": ... }) depend on sibling files and cannot be expressed as a glob
+            if (enabled === true && !patterns.includes(glob)) {
+                patterns.push(glob);
+            }
+        }
+    }
+    
+    if (patterns.length === 0) {
+        return undefined;
+    }
+    return patterns.length === 1 ? patterns[0] : `{${patterns.join(',')}}`;
+}
+
+/**
+ * Check a file against the testFiles setting
+ */
+function matchesTestFilter(uri: vscode.Uri): boolean {
+    const isTest = uri.path.endsWith('_test.go');
+    switch (ConfigManager.testFiles) {
+        case 'skip':
+            return !isTest;
+        case 'only':
+            return isTest;
+        default:
+            return true;
+    }
+}
+
+/**
+ * Read a file's text, preferring the unsaved editor contents if it is open
+ */
+async function readFileText(uri: vscode.Uri): Promise<string> {
+    const openDocument = vscode.workspace.textDocuments.find(
+        document => document.uri.toString() === uri.toString()
+    );
+    if (openDocument) {
+        return openDocument.getText();
+    }
+    
+    const bytes = await vscode.workspace.fs.readFile(uri);
+    return Buffer.from(bytes).toString('utf8');
+}
+
+/**
+ * Run an async function over items with a bounded number in flight
+ * Results keep the order of the input items
+ */
+async function mapWithConcurrency<T, R>(
+    items: T[],
+    limit: number,
+    fn: (item: T) => Promise<R>
+): Promise<R[]> {
+    const results: R[] = new Array(items.length);
+    let nextIndex = 0;
+    
+    const worker = async (): Promise<void> => {
+        while (nextIndex < items.length) {
+            const index = nextIndex++;
+            results[index] = await fn(items[index]);
+        }
+    };
+    
+    const workerCount = Math.min(limit, items.length);
+    await Promise.all(Array.from({ length: workerCount }, () => worker()));
+    
+    return results;
+}
+
+/**
+ * Add up per-file pattern counts
+ */
+export function sumPatterns(counts: PatternCounts[]): PatternCounts {
+    const total: PatternCounts = {};
+    for (const fileCounts of counts) {
+        for (const [pattern, count] of Object.entries(fileCounts)) {
+            const key = pattern as ErrorHandlingPattern;
+            total[key] = (total[key] || 0) + (count || 0);
+        }
+    }
+    return total;
+}
+
+/**
+ * Count diagnostics per check id
+ * Nothing is counted when diagnostics are disabled, or for generated files and files over maxFileSize
+ */
+export function countChecks(text: string): CheckCounts {
+    const counts: CheckCounts = {};
+    const detector = getDetector();
+    if (!ConfigManager.enableDiagnostics || detector.exceedsMaxFileSize(text) || detector.skipsGeneratedFile(text)) {
+        return counts;
+    }
+    
+    for (const diagnostic of getDiagnosticsManager().computeDiagnostics(text)) {
+        const id = String(diagnostic.code);
+        counts[id] = (counts[id] || 0) + 1;
+    }
+    return counts;
+}
+
+/**
+ * Add up per-file check counts
+ */
+function sumChecks(counts: CheckCounts[]): CheckCounts {
+    const total: CheckCounts = {};
+    for (const fileCounts of counts) {
+        for (const [id, count] of Object.entries(fileCounts)) {
+            total[id] = (total[id] || 0) + count;
+        }
+    }
+    return total;
+}
+
+/**
+ * Print a histogram of counts, most common first
+ */
+function printHistogram(label: string, unit: string, counts: Record<string, number | undefined>): void {
+    const output = getOutputChannel();
+    const entries = Object.entries(counts)
+        .map(([name, count]) => ({ name, count: count || 0 }))
+        .sort((a, b) => b.count - a.count || a.name.localeCompare(b.name));
+    const total = entries.reduce((sum, e) => sum + e.count, 0);
+    const width = Math.max(label.length, 'Total'.length, ...entries.map(e => e.name.length));
+    const countWidth = Math.max(unit.length, 6);
+    
+    output.appendLine('');
+    output.appendLine(`${label.padEnd(width)}  ${unit.padStart(countWidth)}  ${'Share'.padStart(6)}`);
+    for (const { name, count } of entries) {
+        const share = `${Math.round((count / total) * 100)}%`;
+        output.appendLine(`${name.padEnd(width)}  ${String(count).padStart(countWidth)}  ${share.padStart(6)}`);
+    }
+    output.appendLine(`${'Total'.padEnd(width)}  ${String(total).padStart(countWidth)}`);
+}
+
+/**
+ * Print the statistics table to the output channel
+ */
+function printStatistics(
+    results: FileStatistics[],
+    scannedCount: number,
+    patterns: PatternCounts,
+    checks: CheckCounts
+): void {
+    const output = getOutputChannel();
+    const width = Math.max('File'.length, 'Total'.length, ...results.map(r => r.path.length));
+    const row = (path: string, blocks: string | number, linesHidden: string | number): string =>
+        `${path.padEnd(width)}  ${String(blocks).padStart(6)}  ${String(linesHidden).padStart(12)}`;
+    
+    output.clear();
+    output.appendLine(row('File', 'Blocks', 'Lines hidden'));
+    
+    for (const result of results) {
+        output.appendLine(row(result.path, result.blocks, result.linesHidden));
+    }
+    
+    const totalBlocks = results.reduce((sum, r) => sum + r.blocks, 0);
+    const totalLines = results.reduce((sum, r) => sum + r.linesHidden, 0);
+    output.appendLine(row('Total', totalBlocks, totalLines));
+    
+    if (Object.keys(patterns).length > 0) {
+        printHistogram('Pattern', 'Checks', patterns);
+    }
+    if (Object.keys(checks).length > 0) {
+        printHistogram('Check', 'Findings', checks);
+    }
+    
+    output.appendLine('');
+    output.appendLine(
+        `Scanned ${scannedCount} Go file(s), ${results.length} with collapsible error blocks`
+    );
+    output.show(true);
+}
+
+/**
+ * Report collapsible error blocks for every Go file in the workspace
+ * Read-only: no files or fold states are changed
+ */
+export async function showStatistics(): Promise<void> {
+    const files = (await vscode.workspace.findFiles('**/*.go', buildExcludePattern()))
+        .filter(matchesTestFilter);
+    
+    if (files.length === 0) {
+        vscode.window.showInformationMessage('Go Error Collapse: No Go files found in the workspace');
+        return;
+    }
+    
+    const detector = getDetector();
+    
+    if (scanCache.size > SCAN_CACHE_LIMIT) {
+        scanCache.clear();
+    }
+    
+    const times: Record<ScanPhase, number> = { read: 0, detect: 0, patterns: 0, checks: 0 };
+    let cached = 0;
+    
+    // File reads overlap; detection itself is synchronous
+    // Unchanged files reuse the result of an earlier scan
+    const scanned = await mapWithConcurrency(files, SCAN_CONCURRENCY, async uri => {
+        const path = vscode.workspace.asRelativePath(uri);
+        const readStart = performance.now();
+        const text = await readFileText(uri);
+        times.read += performance.now() - readStart;
+        const key = scanCacheKey(text);
+        
+        let counts = scanCache.get(key);
+        if (counts) {
+            cached++;
+        } else {
+            const blocks = timed(times, 'detect', () => detector.detectInText(text, path));
+            counts = {
+                blocks: blocks.length,
+                linesHidden: countHiddenLines(blocks),
+                patterns: timed(times, 'patterns', () => detector.countPatterns(text)),
+                checks: timed(times, 'checks', () => countChecks(text)),
+            };
+            scanCache.set(key, counts);
+        }
+        
+        const stats: FileStatistics = { path, ...counts };
+        return stats;
+    });
+    
+    // Sort so output does not depend on read completion order
+    const results = scanned.filter(stats => stats.blocks > 0);
+    results.sort((a, b) => a.path.localeCompare(b.path));
+    printStatistics(
+        results,
+        files.length,
+        sumPatterns(scanned.map(stats => stats.patterns)),
+        sumChecks(scanned.map(stats => stats.checks))
+    );
+    
+    if (ConfigManager.trace) {
+        printPhaseTimes(times, cached);
+    }
+}
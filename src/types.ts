@@ -24,6 +24,49 @@ export interface ErrorBlock {
     
     /** The body statement (return, panic, etc.) */
     bodyStatement: string;
+    
+    /** Line comment after the closing "}", which folding would hide */
+    closingComment?: string;
+}
+
+/**
+ * A top-level function or method declaration
+ */
+export interface FunctionRange {
+    /** Function name, without the receiver */
+    name: string;
+    
+    /** Line number of the "func" keyword (0-indexed) */
+    startLine: number;
+    
+    /** Line number of the closing "}" (0-indexed) */
+    endLine: number;
+}
+
+/**
+ * An "if err != nil {" block found in the source, before any collapse rules are applied
+ */
+export interface ErrorCheck {
+    /** Line number of "if err != nil {" (0-indexed) */
+    startLine: number;
+    
+    /** Line number of closing "}" (0-indexed), or of "} else" when the block has an else clause */
+    endLine: number;
+    
+    /** First line of the block body (0-indexed) */
+    bodyStartLine: number;
+    
+    /** Original indentation of the if statement */
+    indentation: string;
+    
+    /** Name of the checked error variable */
+    errorVar: string;
+    
+    /** Raw lines between the braces */
+    bodyLines: string[];
+    
+    /** Whether an else or else-if clause follows the block */
+    hasElse: boolean;
 }
 
 /**
@@ -44,6 +87,42 @@ export interface ExtensionConfig {
     
     /** Variable names to recognize as error types */
     errorPatterns: string[];
+    
+    /** Report likely bugs in error handling code as diagnostics */
+    enableDiagnostics: boolean;
+    
+    /** Glob patterns skipped by workspace-wide scans */
+    exclude: string[];
+    
+    /** Whether workspace-wide scans cover _test.go files: include them, skip them or scan only them */
+    testFiles: 'include' | 'skip' | 'only';
+    
+    /** Collapse error blocks in generated files ("// Code generated ... DO NOT EDIT.") */
+    processGeneratedFiles: boolean;
+    
+    /** Only collapse blocks that return the checked error unmodified */
+    passthroughOnly: boolean;
+    
+    /** Minimum number of error blocks a file needs before it is auto-collapsed */
+    minBlocks: number;
+    
+    /** Log why each error block was or was not collapsed */
+    trace: boolean;
+    
+    /** Only collapse in files or functions marked //errcollapse:enable */
+    optIn: boolean;
+    
+    /** Extra regexes for statements allowed inside collapsible error blocks */
+    customStatementPatterns: string[];
+    
+    /** Diagnostic check ids to turn on in addition to the defaults */
+    enabledChecks: string[];
+    
+    /** Diagnostic check ids to turn off (applied after enabledChecks) */
+    disabledChecks: string[];
+    
+    /** Skip files larger than this many bytes (0 = no limit) */
+    maxFileSize: number;
 }
 
 /**
@@ -70,3 +149,185 @@ export interface CollapseState {
     /** Whether transparency mode is active */
     isTransparent: boolean;
 }
+
+/**
+ * Error block statistics for a single file
+ */
+export interface FileStatistics {
+    /** Workspace-relative file path */
+    path: string;
+    
+    /** Number of collapsible error blocks */
+    blocks: number;
+    
+    /** Lines hidden when every block is folded */
+    linesHidden: number;
+    
+    /** Number of error checks per handling pattern, collapsible or not */
+    patterns: PatternCounts;
+    
+    /** Number of diagnostics per check id */
+    checks: CheckCounts;
+}
+
+/**
+ * How an "if err != nil" block handles the error
+ * Values are part of the JSON export; add new ones, never rename them
+ */
+export type ErrorHandlingPattern =
+    | 'passthrough'
+    | 'wrapped-w'
+    | 'wrapped-v'
+    | 'logged-and-returned'
+    | 'logged-and-continued'
+    | 'accumulated'
+    | 'fatal'
+    | 'test-fatal'
+    | 'swallowed'
+    | 'other';
+
+/**
+ * Number of error checks per handling pattern
+ */
+export type PatternCounts = Partial<Record<ErrorHandlingPattern, number>>;
+
+/**
+ * Number of diagnostics per check id
+ */
+export type CheckCounts = Record<string, number>;
+
+/**
+ * Replacement that would turn an error block into its one-liner form
+ */
+export interface SuggestedEdit {
+    /** UTF-8 byte offset where the replacement starts */
+    startOffset: number;
+    
+    /** UTF-8 byte offset where the replacement ends (exclusive) */
+    endOffset: number;
+    
+    /** Replacement text */
+    newText: string;
+}
+
+/**
+ * Machine-readable description of a detected error block
+ * Lines and columns are 1-based
+ */
+export interface Finding {
+    /** Workspace-relative file path */
+    file: string;
+    
+    /** Line of the "if" keyword */
+    startLine: number;
+    
+    /** Column of the "if" keyword */
+    startCol: number;
+    
+    /** Line of the closing brace */
+    endLine: number;
+    
+    /** Column just past the end of the closing line */
+    endCol: number;
+    
+    /** Finding category, e.g. "collapsible-error-block" */
+    kind: string;
+    
+    /** One-liner replacement for the block */
+    suggestedEdit: SuggestedEdit;
+    
+    /** Hash of the file, enclosing function and block text; unchanged when other lines move */
+    fingerprint: string;
+}
+
+/**
+ * Stable reason codes for error blocks that are not collapsed
+ * Codes are part of the JSON export; add new ones, never rename them
+ */
+export type SkipReason =
+    | 'ignore-directive'
+    | 'has-else'
+    | 'empty-block'
+    | 'too-many-statements'
+    | 'label-jump'
+    | 'loop-control'
+    | 'unrecognized-statement'
+    | 'multiple-returns'
+    | 'labeled-statement'
+    | 'swallows-error'
+    | 'accumulates-error'
+    | 'not-passthrough'
+    | 'not-opted-in';
+
+/**
+ * Why an error block was not collapsed
+ */
+export interface Rejection {
+    /** Stable reason code */
+    reason: SkipReason;
+    
+    /** Human-readable explanation, as shown in trace output */
+    detail: string;
+}
+
+/**
+ * An error block that was found but not collapsed (0-based lines)
+ */
+export interface SkippedBlock extends Rejection {
+    startLine: number;
+    endLine: number;
+    indentation: string;
+}
+
+/**
+ * Machine-readable description of a skipped error block
+ * Lines and columns are 1-based
+ */
+export interface SkippedFinding extends Rejection {
+    /** Workspace-relative file path */
+    file: string;
+    startLine: number;
+    startCol: number;
+    endLine: number;
+    endCol: number;
+}
+
+/**
+ * Public API returned from activate() for use by other extensions
+ */
+export interface GoErrorCollapseApi {
+    /** Detect error blocks in a document (cached per document version) */
+    detectErrorBlocks(document: vscode.TextDocument): ErrorBlock[];
+    
+    /** Detect error blocks in raw Go source */
+    detectInText(text: string): ErrorBlock[];
+    
+    /** Build machine-readable findings for a document */
+    getFindings(document: vscode.TextDocument): Finding[];
+    
+    /** Get the settings currently in effect */
+    getConfig(): ExtensionConfig;
+    
+    /** Built-in defaults for every setting */
+    readonly defaultConfig: Readonly<ExtensionConfig>;
+}
+
+/**
+ * A diagnostic check the extension can report
+ */
+export interface CheckInfo {
+    /** Stable identifier, used as the diagnostic code */
+    id: string;
+    
+    /** One-line description */
+    description: string;
+    
+    /** Whether the check runs unless disabled */
+    enabledByDefault: boolean;
+    
+    /** Code the check flags, one entry per line */
+    before: string[];
+    
+    /** The same code after fixing it, one entry per line */
+    after: string[];
+}